@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-memdb"
+	"sync"
+	"time"
+)
+
+// auctionChecks remembers the ending auctions whose bids have been checked,
+// bids only grow, so there is no point to check them again
+type auctionChecks struct {
+	mux     sync.Mutex
+	checked map[int64]struct{}
+}
+
+func newAuctionChecks() *auctionChecks {
+	return &auctionChecks{checked: make(map[int64]struct{})}
+}
+
+func (a *auctionChecks) isChecked(id int64) bool {
+	if a == nil {
+		return false
+	}
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	_, ok := a.checked[id]
+	return ok
+}
+
+func (a *auctionChecks) setChecked(id int64) {
+	if a == nil {
+		return
+	}
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	a.checked[id] = struct{}{}
+}
+
+// forgets the auctions that are no longer in the database
+func (a *auctionChecks) forgetRemoved(db *memdb.MemDB) {
+	if a == nil {
+		return
+	}
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	for id := range a.checked {
+		if _, ok := getContractDB(db, id); !ok {
+			delete(a.checked, id)
+		}
+	}
+}
+
+func isAuctionEnding(contract contract, now time.Time, window time.Duration) bool {
+	return contract.Type == auction && contract.DateExpired.After(now) && contract.DateExpired.Sub(now) <= window
+}
+
+// the highest bid, or the starting price when nobody has bid yet
+func getCurrentBid(contract contract, bids []contractBid) float64 {
+	current := contract.Price
+	for _, b := range bids {
+		if b.Amount > current {
+			current = b.Amount
+		}
+	}
+	return current
+}
+
+// alerts when a watched auction is about to end and the current bid is still under our price
+func checkEndingAuction(ctx context.Context, state programState, region string, contract contract, registry map[int64]registryItem, chSignal chan<- registrySignal) {
+	if !isAuctionEnding(contract, time.Now(), state.monitoring.window) || state.monitoring.auctions.isChecked(contract.Id) {
+		return
+	}
+	// the skipped contracts are stored without items as well as the ones found by the first scan
+	passed, err := checkFilters(ctx, state, contract)
+	if err != nil {
+		if ctx.Err() == nil {
+			ifErrorPrint(err)
+		}
+		return
+	}
+	if !passed {
+		state.monitoring.auctions.setChecked(contract.Id)
+		return
+	}
+	// all items of an auction are included, so the watched ones are enough to evaluate it
+	stored, ok := getContractDB(state.monitoring.db, contract.Id)
+	if !ok {
+		return
+	}
+	if len(stored.Watched) == 0 {
+		// the contracts found by the first scan of the region are stored without items
+		items, err := loadContractItems(ctx, state.eve, contract.Id)
+		if err != nil {
+			if ctx.Err() == nil {
+				ifErrorPrint(err)
+			}
+			return
+		}
+		stored.Watched = getWatchedItems(registry, items)
+	}
+	if len(stored.Watched) == 0 || checkConstraints(registry, stored.Watched) != nil {
+		state.monitoring.auctions.setChecked(contract.Id)
+		return
+	}
+	bids, err := loadContractBids(ctx, state.eve, contract.Id)
+	if err != nil {
+		if ctx.Err() == nil {
+			ifErrorPrint(err)
+		}
+		return
+	}
+	state.monitoring.auctions.setChecked(contract.Id)
+	bid := getCurrentBid(contract, bids)
+	fmt.Fprintf(state.monitoring.logger, "auction %d ends at %s, current bid: %0.3f M\n", contract.Id, contract.DateExpired.Format(time.RFC3339), bid/1000000)
+	if checkPrice(registry, bid, stored.Watched) {
+		fmt.Fprintln(state.monitoring.logger, "FOUND AUCTION")
+		chSignal <- registrySignal{
+			region:   region,
+			contract: contract,
+			items:    stored.Watched,
+			bid:      bid,
+			warnings: getScamWarnings(registry, contract, stored.Watched),
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func Test_isAuctionEnding(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		contract contract
+		want     bool
+	}{
+		{
+			name:     "ending",
+			contract: contract{Type: auction, DateExpired: now.Add(time.Minute * 30)},
+			want:     true,
+		},
+		{
+			name:     "far from the end",
+			contract: contract{Type: auction, DateExpired: now.Add(time.Hour * 2)},
+			want:     false,
+		},
+		{
+			name:     "expired",
+			contract: contract{Type: auction, DateExpired: now.Add(-time.Minute)},
+			want:     false,
+		},
+		{
+			name:     "not an auction",
+			contract: contract{Type: itemExchange, DateExpired: now.Add(time.Minute * 30)},
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAuctionEnding(tt.contract, now, time.Hour); got != tt.want {
+				t.Errorf("isAuctionEnding() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getCurrentBid(t *testing.T) {
+	tests := []struct {
+		name string
+		bids []contractBid
+		want float64
+	}{
+		{
+			name: "no bids",
+			want: 1000000,
+		},
+		{
+			name: "highest bid",
+			bids: []contractBid{{BidId: 1, Amount: 2000000}, {BidId: 3, Amount: 5000000}, {BidId: 2, Amount: 3000000}},
+			want: 5000000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getCurrentBid(contract{Type: auction, Price: 1000000}, tt.bids); got != tt.want {
+				t.Errorf("getCurrentBid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkEndingAuction(t *testing.T) {
+	db, err := connectToDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		state    programState
+		chSignal = make(chan registrySignal, 1)
+		registry = map[int64]registryItem{123: {TypeId: 123, Price: 10, TypeName: "Foo"}}
+		auc      = contract{Id: 77, Type: auction, Price: 1000000, DateExpired: time.Now().Add(time.Minute * 10)}
+	)
+	state.eve = eveConnector{client: &pathClientTest{data: map[string]string{
+		"/latest/contracts/public/items/77": `[{"record_id":1,"type_id":123,"is_included":true,"is_blueprint_copy":true,"runs":5,"quantity":1}]`,
+		"/latest/contracts/public/bids/77":  `[{"bid_id":1,"amount":30000000}]`,
+	}}}
+	state.monitoring.db = db
+	state.monitoring.window = time.Hour
+	state.monitoring.auctions = newAuctionChecks()
+	state.monitoring.locations = locationFilter{maxJumps: -1, minSecurity: -1}
+	state.monitoring.logger = ioutil.Discard
+	// stored by the first scan of the region, without items
+	isNewlyCreatedContractCheckDB(db, regionIdJita, auc)
+
+	checkEndingAuction(context.Background(), state, regionIdJita, auc, registry, chSignal)
+	select {
+	case sig := <-chSignal:
+		if sig.bid != 30000000 || len(sig.items) != 1 {
+			t.Errorf("unexpected signal %+v", sig)
+		}
+	default:
+		t.Fatal("expected an alert on the ending auction")
+	}
+	if !state.monitoring.auctions.isChecked(auc.Id) {
+		t.Error("the auction is not marked as checked")
+	}
+	state.monitoring.auctions.forgetRemoved(db)
+	if !state.monitoring.auctions.isChecked(auc.Id) {
+		t.Error("the auction in the database is forgotten")
+	}
+	removeExpiredContractsDB(db, auc.DateExpired.Add(time.Minute), time.Hour)
+	state.monitoring.auctions.forgetRemoved(db)
+	if state.monitoring.auctions.isChecked(auc.Id) {
+		t.Error("the removed auction is not forgotten")
+	}
+}
+
+func Test_checkEndingAuction_filtered(t *testing.T) {
+	tests := []struct {
+		name     string
+		location int64
+		setup    func(state *programState)
+	}{
+		{
+			name:     "blocked issuer",
+			location: 60003760,
+			setup: func(state *programState) {
+				state.monitoring.issuers = newIssuerFilter(programConfig{BlockedIssuers: []int64{90000001}})
+			},
+		},
+		{
+			name:     "unknown structure",
+			location: 1022734985679,
+			setup: func(state *programState) {
+				state.monitoring.locations.minSecurity = 0.5
+			},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := connectToDatabase()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var (
+				state    programState
+				id       = int64(100 + i)
+				client   = &pathClientTest{data: map[string]string{}}
+				chSignal = make(chan registrySignal, 1)
+				registry = map[int64]registryItem{123: {TypeId: 123, Price: 10, TypeName: "Foo"}}
+				auc      = contract{Id: id, Type: auction, Price: 1000000, IssuerId: 90000001, StartLocationId: tt.location, DateExpired: time.Now().Add(time.Minute * 10)}
+			)
+			client.data[fmt.Sprintf("/latest/contracts/public/items/%d", id)] = `[{"record_id":1,"type_id":123,"is_included":true,"is_blueprint_copy":true,"runs":5,"quantity":1}]`
+			client.data[fmt.Sprintf("/latest/contracts/public/bids/%d", id)] = `[{"bid_id":1,"amount":30000000}]`
+			state.eve = eveConnector{client: client, universe: newUniverseCache()}
+			state.monitoring.db = db
+			state.monitoring.window = time.Hour
+			state.monitoring.auctions = newAuctionChecks()
+			state.monitoring.locations = locationFilter{maxJumps: -1, minSecurity: -1}
+			state.monitoring.logger = ioutil.Discard
+			tt.setup(&state)
+			// skipped by the filters and stored without items
+			isNewlyCreatedContractCheckDB(db, regionIdJita, auc)
+
+			checkEndingAuction(context.Background(), state, regionIdJita, auc, registry, chSignal)
+			if len(chSignal) != 0 {
+				t.Errorf("unexpected alert %+v", <-chSignal)
+			}
+			if client.requests != 0 {
+				t.Errorf("expected no requests for the items and bids, got %d", client.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+const configPath = "./config.json"
+
+// programConfig holds the settings that are rarely changed, the command line flags take precedence over them
+type programConfig struct {
+	EsiUrl     string `json:"esi_url"`
+	Datasource string `json:"datasource"`
+	// the contracts of the blocked issuers are skipped, if there are allow lists only the listed issuers are checked
+	BlockedIssuers      []int64 `json:"blocked_issuers"`
+	AllowedIssuers      []int64 `json:"allowed_issuers"`
+	BlockedCorporations []int64 `json:"blocked_corporations"`
+	AllowedCorporations []int64 `json:"allowed_corporations"`
+	// the receivers of the webhook notifier
+	Webhooks []webhookConfig `json:"webhooks"`
+}
+
+func loadConfig() programConfig {
+	var config = programConfig{
+		EsiUrl:     defaultEsiUrl,
+		Datasource: defaultDatasource,
+	}
+	f, err := os.Open(configPath)
+	if err != nil {
+		// the config file is optional
+		if !os.IsNotExist(err) {
+			ifErrorPrint(err)
+		}
+		return config
+	}
+	defer deferWithPrintError(f.Close)
+	ifErrorFatal(json.NewDecoder(f).Decode(&config))
+	return config
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// courierRules are the conditions our haulers accept, zero values are not checked
+type courierRules struct {
+	enabled       bool
+	minPerJump    float64 // millions of ISK per jump
+	minPerM3      float64 // ISK per cubic meter
+	maxCollateral float64 // millions of ISK
+}
+
+// returns the reward per jump and per cubic meter, the packages smaller than a cubic meter are counted as one
+func getCourierRates(contract contract, jumps int) (perJump, perM3 float64) {
+	if jumps < 1 {
+		jumps = 1
+	}
+	volume := contract.Volume
+	if volume < 1 {
+		volume = 1
+	}
+	return contract.Reward / float64(jumps), contract.Reward / volume
+}
+
+// checks the cargo of the courier contract, this can be done before the route is known
+func checkCourierCargo(rules courierRules, contract contract) bool {
+	if contract.Reward <= 0 {
+		return false
+	}
+	if rules.maxCollateral > 0 && contract.Collateral/1000000 > rules.maxCollateral {
+		return false
+	}
+	_, perM3 := getCourierRates(contract, 1)
+	return perM3 >= rules.minPerM3
+}
+
+func checkCourierRoute(rules courierRules, contract contract, jumps int) bool {
+	perJump, _ := getCourierRates(contract, jumps)
+	return perJump/1000000 >= rules.minPerJump
+}
+
+func monCheckCourier(ctx context.Context, region string, contract contract, eve eveConnector, rules courierRules, chSignal chan<- registrySignal, logger io.Writer) error {
+	if !checkCourierCargo(rules, contract) {
+		return nil
+	}
+	jumps, err := eve.getLocationJumps(ctx, contract.StartLocationId, contract.EndLocationId)
+	if err == errUnknownLocation || err == errNoRoute {
+		// the route to a player structure or an unreachable system is unknown, there is no point in trying again
+		fmt.Fprintf(logger, "courier contract %d skipped: %s\n", contract.Id, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if checkCourierRoute(rules, contract, jumps) {
+		fmt.Fprintln(logger, "FOUND COURIER")
+		chSignal <- registrySignal{
+			region:   region,
+			contract: contract,
+			jumps:    jumps,
+		}
+	}
+	return nil
+}
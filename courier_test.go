@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+func Test_checkCourier(t *testing.T) {
+	rules := courierRules{
+		enabled:       true,
+		minPerJump:    2,
+		minPerM3:      1000,
+		maxCollateral: 500,
+	}
+	tests := []struct {
+		name     string
+		contract contract
+		jumps    int
+		want     bool
+	}{
+		{
+			name:     "good",
+			contract: contract{Type: courier, Reward: 20000000, Collateral: 300000000, Volume: 12000},
+			jumps:    8,
+			want:     true,
+		},
+		{
+			name:     "collateral too big",
+			contract: contract{Type: courier, Reward: 20000000, Collateral: 600000000, Volume: 12000},
+			jumps:    8,
+			want:     false,
+		},
+		{
+			name:     "too much volume",
+			contract: contract{Type: courier, Reward: 20000000, Collateral: 300000000, Volume: 60000},
+			jumps:    8,
+			want:     false,
+		},
+		{
+			name:     "too many jumps",
+			contract: contract{Type: courier, Reward: 20000000, Collateral: 300000000, Volume: 12000},
+			jumps:    11,
+			want:     false,
+		},
+		{
+			name:     "same system",
+			contract: contract{Type: courier, Reward: 2000000, Volume: 0.5},
+			jumps:    0,
+			want:     true,
+		},
+		{
+			name:     "no reward",
+			contract: contract{Type: courier, Volume: 100},
+			jumps:    1,
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkCourierCargo(rules, tt.contract) && checkCourierRoute(rules, tt.contract, tt.jumps)
+			if got != tt.want {
+				t.Errorf("checkCourier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isMonitoredContract(t *testing.T) {
+	var (
+		registry = map[int64]registryItem{123: {TypeId: 123, Price: 10}}
+		trade    = contract{Type: itemExchange}
+		carry    = contract{Type: courier}
+	)
+	tests := []struct {
+		name     string
+		couriers bool
+		registry map[int64]registryItem
+		contract contract
+		want     bool
+	}{
+		{name: "trade", registry: registry, contract: trade, want: true},
+		{name: "trade in courier only mode", couriers: true, contract: trade, want: false},
+		{name: "courier", couriers: true, contract: carry, want: true},
+		{name: "courier disabled", registry: registry, contract: carry, want: false},
+		{name: "corporation", registry: registry, contract: contract{Type: itemExchange, ForCorporation: true}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := monitoringState{courier: courierRules{enabled: tt.couriers}}
+			if got := isMonitoredContract(state, tt.registry, tt.contract); got != tt.want {
+				t.Errorf("isMonitoredContract() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_monCheckCourier_noRoute(t *testing.T) {
+	client := &pathClientTest{data: map[string]string{
+		"/latest/universe/stations/60003760": `{"station_id":60003760,"system_id":30000142}`,
+		"/latest/universe/stations/60015001": `{"station_id":60015001,"system_id":30045328}`,
+	}}
+	var (
+		eve      = eveConnector{client: client, universe: newUniverseCache()}
+		chSignal = make(chan registrySignal, 1)
+		carry    = contract{Id: 1, Type: courier, Reward: 50000000, Volume: 1000, StartLocationId: 60003760, EndLocationId: 60015001}
+	)
+	if err := monCheckCourier(context.Background(), regionIdJita, carry, eve, courierRules{enabled: true}, chSignal, ioutil.Discard); err != nil {
+		t.Errorf("expected the contract to be skipped, got %v", err)
+	}
+	if len(chSignal) != 0 {
+		t.Error("unexpected alert")
+	}
+}
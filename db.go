@@ -1,10 +1,15 @@
 package main
 
-import "github.com/hashicorp/go-memdb"
+import (
+	"github.com/hashicorp/go-memdb"
+	"time"
+)
 
 const (
 	tableContracts = "contracts"
+	tablePending   = "pending"
 	indexContracts = "Id"
+	indexRegion    = "Region"
 )
 
 func connectToDatabase() (*memdb.MemDB, error) {
@@ -18,6 +23,25 @@ func connectToDatabase() (*memdb.MemDB, error) {
 						Unique:  true,
 						Indexer: &memdb.IntFieldIndex{Field: indexContracts},
 					},
+					"region": {
+						Name:    "region",
+						Indexer: &memdb.StringFieldIndex{Field: indexRegion},
+					},
+				},
+			},
+			// contracts whose items could not be loaded
+			tablePending: {
+				Name: tablePending,
+				Indexes: map[string]*memdb.IndexSchema{
+					"id": {
+						Name:    "id",
+						Unique:  true,
+						Indexer: &memdb.IntFieldIndex{Field: indexContracts},
+					},
+					"region": {
+						Name:    "region",
+						Indexer: &memdb.StringFieldIndex{Field: indexRegion},
+					},
 				},
 			},
 		},
@@ -33,12 +57,179 @@ func isExistsContractCheckDB(db *memdb.MemDB, contract contract) (exists bool) {
 	return e != nil
 }
 
-func isNewlyCreatedContractCheckDB(db *memdb.MemDB, contract contract) (inserted bool) {
+// the region is known if at least one of its contracts has been seen before
+func isRegionKnownDB(db *memdb.MemDB, region string) bool {
+	txn := db.Txn(false)
+	e, err := txn.First(tableContracts, "region", region)
+	ifErrorFatal(err)
+	txn.Commit()
+	return e != nil
+}
+
+func isNewlyCreatedContractCheckDB(db *memdb.MemDB, region string, contract contract) (inserted bool) {
 	if isExistsContractCheckDB(db, contract) {
 		return false
 	}
+	var now = time.Now()
+	// the raw contract is only needed for logging, there is no point in keeping it in memory
+	contract.Raw = nil
 	txn := db.Txn(true)
-	ifErrorFatal(txn.Insert(tableContracts, contract))
+	ifErrorFatal(txn.Insert(tableContracts, regionContract{
+		Region:    region,
+		FirstSeen: now,
+		LastSeen:  now,
+		contract:  contract,
+	}))
 	txn.Commit()
 	return true
 }
+
+func getContractDB(db *memdb.MemDB, id int64) (regionContract, bool) {
+	txn := db.Txn(false)
+	e, err := txn.First(tableContracts, "id", id)
+	ifErrorFatal(err)
+	txn.Commit()
+	if e == nil {
+		return regionContract{}, false
+	}
+	return e.(regionContract), true
+}
+
+func updateContractDB(db *memdb.MemDB, contract regionContract) {
+	txn := db.Txn(true)
+	ifErrorFatal(txn.Insert(tableContracts, contract))
+	txn.Commit()
+}
+
+func addPendingContractDB(db *memdb.MemDB, region string, contract contract) {
+	txn := db.Txn(true)
+	ifErrorFatal(txn.Insert(tablePending, regionContract{Region: region, contract: contract}))
+	txn.Commit()
+}
+
+func removePendingContractDB(db *memdb.MemDB, contract regionContract) {
+	txn := db.Txn(true)
+	ifErrorFatal(txn.Delete(tablePending, contract))
+	txn.Commit()
+}
+
+func getPendingContractsDB(db *memdb.MemDB, region string) (contracts []regionContract) {
+	txn := db.Txn(false)
+	it, err := txn.Get(tablePending, "region", region)
+	ifErrorFatal(err)
+	for e := it.Next(); e != nil; e = it.Next() {
+		contracts = append(contracts, e.(regionContract))
+	}
+	txn.Commit()
+	return
+}
+
+// updates the history of the region contracts after the full scan. The contract that is not found before
+// its expiration was accepted or withdrawn, it is kept for reports if it contains watched items, otherwise removed.
+// The contract not seen since the start of the monitoring disappeared while it was down, the moment is unknown,
+// so it is removed too. The disappeared contract found again was only missed by a scan, it is returned as reappeared
+func updateLifecycleDB(db *memdb.MemDB, region string, found map[int64]struct{}, now, started time.Time) (disappeared, reappeared []regionContract, removed int) {
+	txn := db.Txn(true)
+	it, err := txn.Get(tableContracts, "region", region)
+	ifErrorFatal(err)
+	var (
+		updated []regionContract
+		missing []interface{}
+	)
+	for e := it.Next(); e != nil; e = it.Next() {
+		c := e.(regionContract)
+		switch _, ok := found[c.Id]; {
+		case ok:
+			c.LastSeen = now
+			if !c.Disappeared.IsZero() {
+				c.Disappeared = time.Time{}
+				reappeared = append(reappeared, c)
+			}
+			updated = append(updated, c)
+		case !c.Disappeared.IsZero():
+			// already known as disappeared
+		case len(c.Watched) == 0 || !now.Before(c.DateExpired) || c.LastSeen.Before(started):
+			missing = append(missing, e)
+		default:
+			c.Disappeared = now
+			updated = append(updated, c)
+			disappeared = append(disappeared, c)
+		}
+	}
+	for _, c := range updated {
+		ifErrorFatal(txn.Insert(tableContracts, c))
+	}
+	for _, e := range missing {
+		ifErrorFatal(txn.Delete(tableContracts, e))
+	}
+	txn.Commit()
+	return disappeared, reappeared, len(missing)
+}
+
+// the disappeared contracts are kept for reports during the retention period
+func isOutdatedContract(c regionContract, now time.Time, retention time.Duration) bool {
+	if c.Disappeared.IsZero() {
+		return c.DateExpired.Before(now)
+	}
+	return c.Disappeared.Before(now.Add(-retention))
+}
+
+func removeExpiredContractsDB(db *memdb.MemDB, now time.Time, retention time.Duration) (removed int) {
+	txn := db.Txn(true)
+	it, err := txn.Get(tableContracts, "id")
+	ifErrorFatal(err)
+	var expired []interface{}
+	for e := it.Next(); e != nil; e = it.Next() {
+		if isOutdatedContract(e.(regionContract), now, retention) {
+			expired = append(expired, e)
+		}
+	}
+	for _, e := range expired {
+		ifErrorFatal(txn.Delete(tableContracts, e))
+	}
+	txn.Commit()
+	return len(expired)
+}
+
+func countContractsDB(db *memdb.MemDB) (count int) {
+	txn := db.Txn(false)
+	it, err := txn.Get(tableContracts, "id")
+	ifErrorFatal(err)
+	for e := it.Next(); e != nil; e = it.Next() {
+		count++
+	}
+	txn.Commit()
+	return
+}
+
+// returns all contracts except those that are waiting for retry
+func getCheckedContractsDB(db *memdb.MemDB) (contracts []regionContract) {
+	txn := db.Txn(false)
+	it, err := txn.Get(tableContracts, "id")
+	ifErrorFatal(err)
+	for e := it.Next(); e != nil; e = it.Next() {
+		c := e.(regionContract)
+		pending, err := txn.First(tablePending, "id", c.Id)
+		ifErrorFatal(err)
+		if pending == nil {
+			contracts = append(contracts, c)
+		}
+	}
+	txn.Commit()
+	return
+}
+
+// returns contracts with watched items that disappeared before expiration since the specified moment
+func getSoldContractsDB(db *memdb.MemDB, since time.Time) (contracts []regionContract) {
+	txn := db.Txn(false)
+	it, err := txn.Get(tableContracts, "id")
+	ifErrorFatal(err)
+	for e := it.Next(); e != nil; e = it.Next() {
+		c := e.(regionContract)
+		if len(c.Watched) > 0 && c.Disappeared.After(since) && c.Disappeared.Before(c.DateExpired) {
+			contracts = append(contracts, c)
+		}
+	}
+	txn.Commit()
+	return
+}
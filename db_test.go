@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_pendingContractsDB(t *testing.T) {
+	db, err := connectToDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := int64(1); id <= 3; id++ {
+		addPendingContractDB(db, regionIdJita, contract{Id: id, Type: itemExchange})
+	}
+	addPendingContractDB(db, "10000043", contract{Id: 4, Type: itemExchange})
+	removePendingContractDB(db, regionContract{contract: contract{Id: 2}})
+	pending := getPendingContractsDB(db, regionIdJita)
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 pending contracts, got %d", len(pending))
+	}
+	if pending[0].Id != 1 || pending[1].Id != 3 {
+		t.Errorf("unexpected pending contracts: %v", pending)
+	}
+}
+
+func Test_pruneContractsDB(t *testing.T) {
+	db, err := connectToDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var now = time.Now()
+	for id := int64(1); id <= 4; id++ {
+		isNewlyCreatedContractCheckDB(db, regionIdJita, contract{Id: id, DateExpired: now.Add(time.Hour)})
+	}
+	isNewlyCreatedContractCheckDB(db, regionIdJita, contract{Id: 5, DateExpired: now.Add(-time.Hour)})
+	isNewlyCreatedContractCheckDB(db, "10000043", contract{Id: 6, DateExpired: now.Add(time.Hour)})
+	addPendingContractDB(db, regionIdJita, contract{Id: 4, DateExpired: now.Add(time.Hour)})
+	watched, _ := getContractDB(db, 2)
+	watched.Watched = []contractItem{{TypeId: 123, Runs: 10, Quantity: 1, IsIncluded: true}}
+	updateContractDB(db, watched)
+	// restored from the log, it has not been seen since the start
+	isNewlyCreatedContractCheckDB(db, regionIdJita, contract{Id: 7, DateExpired: now.Add(time.Hour)})
+	restored, _ := getContractDB(db, 7)
+	restored.Watched = watched.Watched
+	restored.LastSeen = now.Add(-time.Hour)
+	updateContractDB(db, restored)
+
+	if removed := removeExpiredContractsDB(db, now, time.Hour); removed != 1 {
+		t.Errorf("expected 1 expired contract, got %d", removed)
+	}
+	// the contract with watched items is kept as sold
+	found := map[int64]struct{}{1: {}, 4: {}}
+	disappeared, reappeared, removed := updateLifecycleDB(db, regionIdJita, found, now, now.Add(-time.Minute))
+	if removed != 2 || len(disappeared) != 1 || disappeared[0].Id != 2 || len(reappeared) != 0 {
+		t.Errorf("unexpected disappeared contracts: %v, removed %d", disappeared, removed)
+	}
+	if count := countContractsDB(db); count != 4 {
+		t.Errorf("expected 4 contracts in the table, got %d", count)
+	}
+	if checked := getCheckedContractsDB(db); len(checked) != 3 {
+		t.Errorf("expected 3 checked contracts, got %d", len(checked))
+	}
+	if sold := getSoldContractsDB(db, now.Add(-time.Minute)); len(sold) != 1 {
+		t.Errorf("expected 1 sold contract, got %d", len(sold))
+	}
+	// the contract was only missed by the previous scan
+	found[2] = struct{}{}
+	later := now.Add(time.Minute)
+	disappeared, reappeared, _ = updateLifecycleDB(db, regionIdJita, found, later, now.Add(-time.Minute))
+	if len(disappeared) != 0 || len(reappeared) != 1 || reappeared[0].Id != 2 || !reappeared[0].Disappeared.IsZero() {
+		t.Errorf("unexpected reappeared contracts: %v", reappeared)
+	}
+	if sold := getSoldContractsDB(db, now.Add(-time.Minute)); len(sold) != 0 {
+		t.Errorf("expected no sold contracts, got %d", len(sold))
+	}
+	if removed := removeExpiredContractsDB(db, now.Add(time.Hour*2), time.Hour); removed != 4 {
+		t.Errorf("expected 4 outdated contracts, got %d", removed)
+	}
+}
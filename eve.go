@@ -2,11 +2,12 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"math"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -16,15 +17,18 @@ import (
 )
 
 const (
-	host = "esi.evetech.net"
-
-	eveServer    = "tranquility"
-	regionIdJita = "10000002"
+	defaultEsiUrl     = "https://esi.evetech.net"
+	defaultDatasource = "tranquility"
+	regionIdJita      = "10000002"
 
 	apiContracts     = "/latest/contracts/public/"
 	apiContractItems = "/latest/contracts/public/items/"
+	apiContractBids  = "/latest/contracts/public/bids/"
 
 	ifNoneMatchHeader = "If-None-Match"
+	xPagesHeader      = "X-Pages"
+	expiresHeader     = "Expires"
+	dateHeader        = "Date"
 	typesUrl          = "https://eve-files.com/chribba/typeid.txt"
 )
 
@@ -38,80 +42,214 @@ func checkResponse(resp *http.Response) error {
 	return nil
 }
 
-func executeQuery(client httpClient, path, itemId string, page int, data interface{}, eTagsA ...*sync.Map) error {
+// returns the number of pages reported by ESI, a response without the header is considered a single page
+func getPagesCount(header http.Header) int {
+	pages, err := strconv.Atoi(header.Get(xPagesHeader))
+	if err != nil || pages < 1 {
+		return 1
+	}
+	return pages
+}
+
+func (c *eveConnector) executeQuery(ctx context.Context, path, itemId string, page int, data interface{}, eTagsA ...*sync.Map) (http.Header, error) {
 	var (
-		etag             = ""
+		cached cachedPage
 		eTags  *sync.Map = nil
 		key              = fmt.Sprintf("%s%s?%d", path, itemId, page)
-		newUrl           = url.URL{
-			Scheme: "https",
-			Host:   host,
-			Path:   path + itemId,
-		}
 	)
-	if c := len(eTagsA); c > 0 {
-		if c != 1 {
-			return errors.New("only one eTags argument expected")
+	newUrl, err := c.getUrl(path + itemId)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(eTagsA); n > 0 {
+		if n != 1 {
+			return nil, errors.New("only one eTags argument expected")
 		}
 		if eTags = eTagsA[0]; eTags != nil {
-			if etagI, ok := eTags.Load(key); ok {
-				etag = etagI.(string)
+			if cachedI, ok := eTags.Load(key); ok {
+				cached = cachedI.(cachedPage)
 			}
 		}
 	}
 	newUrl.RawQuery = url.Values{
-		"datasource": []string{eveServer},
+		"datasource": []string{c.getDatasource()},
 		"page":       []string{strconv.Itoa(page)},
 	}.Encode()
-	req, err := http.NewRequest(http.MethodGet, newUrl.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, newUrl.String(), nil)
 	ifErrorFatal(err)
 	// this header will save time on pages that have not been changed
-	req.Header.Add(ifNoneMatchHeader, etag)
-	resp, err := client.Do(req)
+	req.Header.Add(ifNoneMatchHeader, cached.etag)
+	resp, err := c.getClient().Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer deferWithPrintError(resp.Body.Close)
 	if err := checkResponse(resp); err != nil {
-		return err
+		return resp.Header, err
 	}
 	if resp.StatusCode == 200 {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return resp.Header, err
+		}
 		if eTags != nil {
-			eTags.Store(key, resp.Header.Get("etag"))
+			eTags.Store(key, cachedPage{etag: resp.Header.Get("etag"), body: body, pages: getPagesCount(resp.Header)})
 		}
-		dec := json.NewDecoder(resp.Body)
-		return dec.Decode(&data)
+		return resp.Header, json.Unmarshal(body, &data)
+	}
+	// the page has not been changed, but the caller has to see all its data again
+	if resp.StatusCode == http.StatusNotModified && cached.body != nil {
+		// a 304 is not obliged to repeat X-Pages, a caching proxy may drop it
+		var header = resp.Header
+		if header.Get(xPagesHeader) == "" {
+			header = header.Clone()
+			if header == nil {
+				header = make(http.Header)
+			}
+			header.Set(xPagesHeader, strconv.Itoa(cached.pages))
+		}
+		return header, json.Unmarshal(cached.body, &data)
 	}
 	// there is no point in looking for data in an response other than status 200
-	return nil
+	return resp.Header, nil
 }
 
-type eveConnector struct {
-	client httpClient
+type (
+	// cachedPage keeps the last received page, so the full list of contracts is known even if it has not been changed
+	cachedPage struct {
+		etag  string
+		body  []byte
+		pages int
+	}
+	eveConnector struct {
+		client     httpClient
+		baseUrl    string
+		datasource string
+		limiter    *errorLimiter
+		expires    *pagesExpiry
+		retry      retryPolicy
+		universe   *universeCache
+	}
+	// pagesExpiry remembers when ESI is going to refresh the cached pages
+	pagesExpiry struct {
+		mux   sync.Mutex
+		pages map[string]time.Time
+	}
+)
+
+func newPagesExpiry() *pagesExpiry {
+	return &pagesExpiry{pages: make(map[string]time.Time)}
 }
 
-var eTags sync.Map
+// returns the local time when the response expires, the difference between clocks is compensated by the Date header
+func getExpiry(header http.Header) (time.Time, bool) {
+	expires, err := http.ParseTime(header.Get(expiresHeader))
+	if err != nil {
+		return time.Time{}, false
+	}
+	if date, err := http.ParseTime(header.Get(dateHeader)); err == nil {
+		return time.Now().Add(expires.Sub(date)), true
+	}
+	return expires, true
+}
 
-func (c *eveConnector) getContracts(regionId string, page int) (data []contract, err error) {
-	var client httpClient = http.DefaultClient
-	if c.client != nil {
-		client = c.client
+func (e *pagesExpiry) store(key string, header http.Header) {
+	if expiry, ok := getExpiry(header); ok {
+		e.mux.Lock()
+		e.pages[key] = expiry
+		e.mux.Unlock()
 	}
-	if err = executeQuery(client, apiContracts, regionId, page, &data, &eTags); err != nil {
+}
+
+// returns the earliest moment when one of the known pages will be refreshed, zero time if nothing is known
+func (e *pagesExpiry) next() (next time.Time) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	var now = time.Now()
+	for _, expiry := range e.pages {
+		if expiry.After(now) && (next.IsZero() || expiry.Before(next)) {
+			next = expiry
+		}
+	}
+	return
+}
+
+// returns the absolute URL of the ESI method, the base URL may contain a path prefix of a caching proxy
+func (c *eveConnector) getUrl(path string) (*url.URL, error) {
+	var baseUrl = defaultEsiUrl
+	if c.baseUrl != "" {
+		baseUrl = c.baseUrl
+	}
+	u, err := url.Parse(baseUrl)
+	if err != nil {
 		return nil, err
 	}
-	return data, nil
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("wrong ESI URL: %s", baseUrl)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	return u, nil
 }
 
-func (c *eveConnector) getContractItems(contractId string, page int) (data []contractItem, err error) {
+func (c *eveConnector) getDatasource() string {
+	if c.datasource != "" {
+		return c.datasource
+	}
+	return defaultDatasource
+}
+
+// returns the client for ESI requests, all of them share the same error limiter and every retry passes through it
+func (c *eveConnector) getClient() httpClient {
 	var client httpClient = http.DefaultClient
 	if c.client != nil {
 		client = c.client
 	}
-	if err = executeQuery(client, apiContractItems, contractId, page, &data, &eTags); err != nil {
-		return nil, err
+	if c.limiter != nil {
+		client = limitedClient{client: client, limiter: c.limiter}
+	}
+	if c.retry.attempts > 1 {
+		client = retryClient{client: client, policy: c.retry}
+	}
+	return client
+}
+
+var eTags sync.Map
+
+func (c *eveConnector) getContracts(ctx context.Context, regionId string, page int) (data []contract, pages int, err error) {
+	var raw []json.RawMessage
+	header, err := c.executeQuery(ctx, apiContracts, regionId, page, &raw, &eTags)
+	if err != nil {
+		return nil, 0, err
+	}
+	data = make([]contract, len(raw))
+	for i := range raw {
+		if err = json.Unmarshal(raw[i], &data[i]); err != nil {
+			return nil, 0, err
+		}
+		data[i].Raw = raw[i]
+	}
+	if c.expires != nil {
+		c.expires.store(fmt.Sprintf("%s?%d", regionId, page), header)
+	}
+	return data, getPagesCount(header), nil
+}
+
+func (c *eveConnector) getContractItems(ctx context.Context, contractId string, page int) (data []contractItem, pages int, err error) {
+	// items of a contract are loaded once, there is no point in caching them
+	header, err := c.executeQuery(ctx, apiContractItems, contractId, page, &data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, getPagesCount(header), nil
+}
+
+func (c *eveConnector) getContractBids(ctx context.Context, contractId string, page int) (data []contractBid, pages int, err error) {
+	// bids are loaded once the auction is about to end
+	header, err := c.executeQuery(ctx, apiContractBids, contractId, page, &data)
+	if err != nil {
+		return nil, 0, err
 	}
-	return data, nil
+	return data, getPagesCount(header), nil
 }
 
 func getItemTypeFromString(s string) (i itemType, err error) {
@@ -157,85 +295,289 @@ func (c *eveConnector) getItemTypes() []itemType {
 	return items
 }
 
+func loadContractsPage(ctx context.Context, eve eveConnector, regionId string, page int, logger io.Writer, conCh chan<- contract) (int, error) {
+	fmt.Fprintf(logger, "processing page %d...\n", page)
+	data, pages, err := eve.getContracts(ctx, regionId, page)
+	if err != nil {
+		return 0, err
+	}
+	for _, contract := range data {
+		conCh <- contract
+	}
+	fmt.Fprintf(logger, "got %d new contracts from page %d\n", len(data), page)
+	return pages, nil
+}
+
 func loadAllContracts(
+	ctx context.Context,
 	eve eveConnector,
 	regionId string,
+	workers int,
 	logger io.Writer,
 	conCh chan<- contract,
 	errCh chan<- error,
 ) {
 	defer close(conCh)
 	defer close(errCh)
-	var page = 1
-	for {
-		fmt.Fprintf(logger, "processing page %d...\n", page)
-		data, err := eve.getContracts(regionId, page)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
+	// the first page tells us how many pages the region has, so the cost of a tick is known in advance
+	pages, err := loadContractsPage(ctx, eve, regionId, 1, logger, conCh)
+	if err != nil {
+		if err != io.EOF {
 			errCh <- err
-			<-time.After(time.Second * 5)
 		}
-		for _, contract := range data {
-			conCh <- contract
+		return
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		wg     sync.WaitGroup
+		pageCh = make(chan int)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range pageCh {
+				// io.EOF means that the number of pages has decreased since the first response
+				if _, err := loadContractsPage(ctx, eve, regionId, page, logger, conCh); err != nil && err != io.EOF {
+					errCh <- err
+				}
+			}
+		}()
+	}
+	for page := 2; page <= pages && ctx.Err() == nil; page++ {
+		select {
+		case pageCh <- page:
+		case <-ctx.Done():
 		}
-		fmt.Fprintf(logger, "got %d new contracts\n", len(data))
-		page++
 	}
+	close(pageCh)
+	wg.Wait()
 }
 
-func loadContractItems(eve eveConnector, contractId int64) ([]contractItem, error) {
+func loadContractItems(ctx context.Context, eve eveConnector, contractId int64) ([]contractItem, error) {
 	var (
-		page  = 1
+		pages = 1
 		items = make([]contractItem, 0, 2)
 	)
-	for {
-		i, err := eve.getContractItems(strconv.FormatInt(contractId, 10), page)
-		if err == io.EOF {
+	for page := 1; page <= pages; page++ {
+		if err := ctx.Err(); err != nil {
+			return items, err
+		}
+		i, p, err := eve.getContractItems(ctx, strconv.FormatInt(contractId, 10), page)
+		// ESI answers 204 with no items when the contract has expired or has just been accepted
+		if err == io.EOF || (err == nil && len(i) == 0) {
 			break
 		}
 		if err != nil {
 			return items, err
 		}
 		items = append(items, i...)
-		page++
+		pages = p
 	}
 	return items, nil
 }
 
+func loadContractBids(ctx context.Context, eve eveConnector, contractId int64) ([]contractBid, error) {
+	var (
+		pages = 1
+		bids  = make([]contractBid, 0)
+	)
+	for page := 1; page <= pages; page++ {
+		if err := ctx.Err(); err != nil {
+			return bids, err
+		}
+		b, p, err := eve.getContractBids(ctx, strconv.FormatInt(contractId, 10), page)
+		if err == io.EOF || (err == nil && len(b) == 0) {
+			break
+		}
+		if err != nil {
+			return bids, err
+		}
+		bids = append(bids, b...)
+		pages = p
+	}
+	return bids, nil
+}
+
+// copies are valued per run and originals per item, ignored kinds and badly researched blueprints are worth nothing to us
+func getItemValue(watch registryItem, item contractItem) float64 {
+	if item.MaterialEfficiency < watch.MinME || item.TimeEfficiency < watch.MinTE {
+		return 0
+	}
+	var value float64
+	if item.Runs < 0 {
+		// this is the original blueprint
+		if watch.IgnoreOriginals {
+			return 0
+		}
+		value = watch.OriginalPrice * float64(item.Quantity)
+	} else {
+		if watch.IgnoreCopies {
+			return 0
+		}
+		value = watch.Price * float64(item.Runs*item.Quantity)
+	}
+	return value * getResearchFactor(watch, item)
+}
+
+// each level of research above the minimum adds its percent to the price, a TE level is 2 points of TE
+func getResearchFactor(watch registryItem, item contractItem) float64 {
+	var (
+		meLevels = float64(item.MaterialEfficiency - watch.MinME)
+		teLevels = float64(item.TimeEfficiency-watch.MinTE) / 2
+	)
+	return 1 + (watch.MEBonus*meLevels+watch.TEBonus*teLevels)/100
+}
+
+// checks the limits of runs and quantity of the watched types, the error tells which limit is broken
+func checkConstraints(registry map[int64]registryItem, items []contractItem) error {
+	var (
+		types    []int64
+		quantity = make(map[int64]int32)
+	)
+	for _, item := range items {
+		watch, ok := registry[item.TypeId]
+		if !ok || !item.IsIncluded {
+			continue
+		}
+		if _, ok = quantity[item.TypeId]; !ok {
+			types = append(types, item.TypeId)
+		}
+		quantity[item.TypeId] += item.Quantity
+		if item.Runs < 0 {
+			// originals have no runs
+			continue
+		}
+		if watch.MinRuns > 0 && item.Runs < watch.MinRuns {
+			return fmt.Errorf("%s has %d runs, less than %d", watch.TypeName, item.Runs, watch.MinRuns)
+		}
+		if watch.MaxRuns > 0 && item.Runs > watch.MaxRuns {
+			return fmt.Errorf("%s has %d runs, more than %d", watch.TypeName, item.Runs, watch.MaxRuns)
+		}
+	}
+	for _, typeId := range types {
+		watch := registry[typeId]
+		if watch.MinQuantity > 0 && quantity[typeId] < watch.MinQuantity {
+			return fmt.Errorf("%s quantity %d is less than %d", watch.TypeName, quantity[typeId], watch.MinQuantity)
+		}
+		if watch.MaxQuantity > 0 && quantity[typeId] > watch.MaxQuantity {
+			return fmt.Errorf("%s quantity %d is more than %d", watch.TypeName, quantity[typeId], watch.MaxQuantity)
+		}
+	}
+	return nil
+}
+
 func checkSuitable(registry map[int64]registryItem, contract contract, items []contractItem) bool {
+	price, ok := getAskingPrice(contract)
+	return ok && checkPrice(registry, price, items)
+}
+
+// the price to get the items right now, auctions without buyout cannot be bought at once
+func getAskingPrice(contract contract) (float64, bool) {
+	if contract.Type == auction {
+		return contract.Buyout, contract.Buyout > 0
+	}
+	return contract.Price, true
+}
+
+// checks that all items are included and are worth the price
+func checkPrice(registry map[int64]registryItem, price float64, items []contractItem) bool {
 	var (
 		excluded              = false
 		contractBound float64 = 0
 	)
 	for _, item := range items {
 		if curr, ok := registry[item.TypeId]; ok {
-			var r = item.Runs
-			if r < 0 {
-				r = math.MaxInt16 // this is the original blueprint, it is very valuable
-			}
-			contractBound += curr.Price * float64(r*item.Quantity)
+			contractBound += getItemValue(curr, item)
 		}
 		if !item.IsIncluded {
 			excluded = true
 			break
 		}
 	}
-	return !excluded && contractBound > 0 && (contract.Price/1000000)-contractBound < 0.001
+	return !excluded && contractBound > 0 && (price/1000000)-contractBound < 0.001
+}
+
+// the price we accept for the requested item, zero if we do not sell it
+func getSellValue(watch registryItem, item contractItem) float64 {
+	if item.Runs < 0 {
+		return watch.SellOriginalPrice * float64(item.Quantity)
+	}
+	return watch.SellPrice * float64(item.Runs*item.Quantity)
+}
+
+// checks the "want to buy" contract: all items are requested from us and the reward is not less than our prices
+func checkWanted(registry map[int64]registryItem, contract contract, items []contractItem) bool {
+	var contractBound float64 = 0
+	for _, item := range items {
+		if item.IsIncluded {
+			return false
+		}
+		curr, ok := registry[item.TypeId]
+		if !ok {
+			return false
+		}
+		value := getSellValue(curr, item)
+		if value <= 0 {
+			return false
+		}
+		contractBound += value
+	}
+	return contractBound > 0 && ((contract.Reward-contract.Price)/1000000)-contractBound > -0.001
 }
 
-func monCheckContract(contract contract, eve eveConnector, registry map[int64]registryItem, chSignal chan<- registrySignal, logger io.Writer) {
-	items, err := loadContractItems(eve, contract.Id)
+// returns the offered items of the contract that are registered for monitoring
+func getWatchedItems(registry map[int64]registryItem, items []contractItem) (watched []contractItem) {
+	for _, item := range items {
+		if _, ok := registry[item.TypeId]; ok && item.IsIncluded {
+			watched = append(watched, item)
+		}
+	}
+	return
+}
+
+func monCheckContract(ctx context.Context, region string, contract contract, eve eveConnector, registry map[int64]registryItem, chSignal chan<- registrySignal, logger io.Writer) ([]contractItem, error) {
+	items, err := loadContractItems(ctx, eve, contract.Id)
 	if err != nil {
-		ifErrorPrint(err)
-		return
+		return nil, err
+	}
+	if err = checkConstraints(registry, items); err != nil {
+		fmt.Fprintf(logger, "contract %d rejected: %s\n", contract.Id, err)
+		return items, nil
+	}
+	warnings := getScamWarnings(registry, contract, items)
+	for _, warning := range warnings {
+		fmt.Fprintf(logger, "contract %d is suspicious: %s\n", contract.Id, warning)
 	}
 	if checkSuitable(registry, contract, items) {
 		fmt.Fprintln(logger, "FOUND")
 		chSignal <- registrySignal{
+			region:   region,
 			contract: contract,
 			items:    items,
+			warnings: warnings,
+		}
+	} else if len(warnings) > 0 && isBaitContract(registry, contract, items) {
+		fmt.Fprintln(logger, "FOUND SUSPICIOUS")
+		chSignal <- registrySignal{
+			region:     region,
+			contract:   contract,
+			items:      items,
+			warnings:   warnings,
+			suspicious: true,
 		}
 	}
+	if checkWanted(registry, contract, items) {
+		fmt.Fprintln(logger, "FOUND WANTED")
+		chSignal <- registrySignal{
+			region:   region,
+			contract: contract,
+			items:    items,
+			wanted:   true,
+			warnings: warnings,
+		}
+	}
+	return items, nil
 }
@@ -1,15 +1,19 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"math/rand"
 	"net/http"
 	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -21,24 +25,35 @@ type (
 	}
 	httpClientTest struct {
 		data     string
+		status   int
+		header   http.Header
 		requests []httpRequested
 	}
 )
 
+// protects httpClientTest requests from concurrent access
+var httpClientTestMux sync.Mutex
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
 func (h *httpClientTest) Do(r *http.Request) (*http.Response, error) {
+	httpClientTestMux.Lock()
+	defer httpClientTestMux.Unlock()
 	h.requests = append(h.requests, httpRequested{
 		url:    r.URL.String(),
 		header: r.Header.Clone(),
 	})
+	var status = http.StatusOK
+	if h.status != 0 {
+		status = h.status
+	}
 	resp := http.Response{
-		Status:           "200 OK",
-		StatusCode:       200,
+		Status:           http.StatusText(status),
+		StatusCode:       status,
 		Proto:            "https",
-		Header:           nil,
+		Header:           h.header,
 		Body:             ioutil.NopCloser(strings.NewReader(h.data)),
 		ContentLength:    int64(len(h.data)),
 		TransferEncoding: nil,
@@ -59,17 +74,19 @@ func makeHttpClientTest(stringData string) httpClientTest {
 
 func Test_executeQuery(t *testing.T) {
 	type args struct {
-		client httpClientTest
-		path   string
-		itemId string
-		page   int
-		data   interface{}
+		client     httpClientTest
+		baseUrl    string
+		datasource string
+		path       string
+		itemId     string
+		page       int
+		data       interface{}
 	}
 	makeDataReceiver := func() interface{} {
 		var r map[string]interface{}
 		return &r
 	}
-	checkResponse := func(a args) error {
+	checkResponse := func(a args, wantHost, wantPrefix, wantDatasource string) error {
 		if len(a.client.requests) != 1 {
 			return fmt.Errorf("expected one request, got %d", len(a.client.requests))
 		}
@@ -77,18 +94,24 @@ func Test_executeQuery(t *testing.T) {
 		if err != nil {
 			return err
 		}
-		if u.Path != a.path+a.itemId {
+		if u.Host != wantHost || u.Path != wantPrefix+a.path+a.itemId {
 			return fmt.Errorf("unexpected URI: %s", a.client.requests[0].url)
 		}
+		if gotDatasource := u.Query().Get("datasource"); gotDatasource != wantDatasource {
+			return fmt.Errorf("unexpected datasource: want %s, got %s", wantDatasource, gotDatasource)
+		}
 		if gotPage := u.Query().Get("page"); gotPage != strconv.Itoa(a.page) {
 			return fmt.Errorf("unexpected page: want %d, got %s", a.page, gotPage)
 		}
 		return nil
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
+		name           string
+		args           args
+		wantHost       string
+		wantPrefix     string
+		wantDatasource string
+		wantErr        bool
 	}{
 		{
 			name: "test-1",
@@ -99,15 +122,48 @@ func Test_executeQuery(t *testing.T) {
 				page:   rand.Int(),
 				data:   makeDataReceiver(),
 			},
-			wantErr: false,
+			wantHost:       "esi.evetech.net",
+			wantDatasource: defaultDatasource,
+			wantErr:        false,
+		},
+		{
+			name: "caching proxy",
+			args: args{
+				client:     makeHttpClientTest("{}"),
+				baseUrl:    "http://localhost:8080/esi/",
+				datasource: "singularity",
+				path:       "/somePath/",
+				itemId:     strconv.Itoa(rand.Int()),
+				page:       rand.Int(),
+				data:       makeDataReceiver(),
+			},
+			wantHost:       "localhost:8080",
+			wantPrefix:     "/esi",
+			wantDatasource: "singularity",
+			wantErr:        false,
+		},
+		{
+			name: "wrong base url",
+			args: args{
+				client:  makeHttpClientTest("{}"),
+				baseUrl: "localhost",
+				path:    "/somePath/",
+				data:    makeDataReceiver(),
+			},
+			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := executeQuery(&tt.args.client, tt.args.path, tt.args.itemId, tt.args.page, tt.args.data); (err != nil) != tt.wantErr {
+			eve := eveConnector{
+				client:     &tt.args.client,
+				baseUrl:    tt.args.baseUrl,
+				datasource: tt.args.datasource,
+			}
+			if _, err := eve.executeQuery(context.Background(), tt.args.path, tt.args.itemId, tt.args.page, tt.args.data); (err != nil) != tt.wantErr {
 				t.Errorf("executeQuery() error = %v, wantErr %v", err, tt.wantErr)
 			} else if err == nil {
-				if err = checkResponse(tt.args); err != nil {
+				if err = checkResponse(tt.args, tt.wantHost, tt.wantPrefix, tt.wantDatasource); err != nil {
 					t.Error(err)
 				}
 			}
@@ -287,3 +343,318 @@ func Test_loadEVEItemTypes(t *testing.T) {
 		})
 	}
 }
+
+func Test_getPagesCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{
+			name:   "no header",
+			header: nil,
+			want:   1,
+		},
+		{
+			name:   "three pages",
+			header: http.Header{xPagesHeader: []string{"3"}},
+			want:   3,
+		},
+		{
+			name:   "wrong value",
+			header: http.Header{xPagesHeader: []string{"foo"}},
+			want:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getPagesCount(tt.header); got != tt.want {
+				t.Errorf("getPagesCount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_loadAllContracts(t *testing.T) {
+	var (
+		client = httpClientTest{
+			data:   `[{"contract_id": 1, "type": "item_exchange"}, {"contract_id": 2, "type": "auction"}]`,
+			header: http.Header{xPagesHeader: []string{"3"}, "Etag": []string{`"abc"`}},
+		}
+		regionId = strconv.Itoa(rand.Int())
+	)
+	loadAll := func() (contracts []contract) {
+		var (
+			conCh = make(chan contract, 10)
+			errCh = make(chan error, 10)
+		)
+		go loadAllContracts(context.Background(), eveConnector{client: &client}, regionId, 2, ioutil.Discard, conCh, errCh)
+		for c := range conCh {
+			contracts = append(contracts, c)
+		}
+		for err := range errCh {
+			t.Error(err)
+		}
+		return
+	}
+	if contracts := loadAll(); len(contracts) != 6 {
+		t.Errorf("expected 6 contracts, got %d", len(contracts))
+	}
+	if len(client.requests) != 3 {
+		t.Errorf("expected 3 requests, got %d", len(client.requests))
+	}
+	// the pages have not been changed and the 304 responses do not repeat X-Pages
+	client.status = http.StatusNotModified
+	client.data = ""
+	client.header = http.Header{"Etag": []string{`"abc"`}}
+	if contracts := loadAll(); len(contracts) != 6 {
+		t.Errorf("expected 6 contracts from the cached pages, got %d", len(contracts))
+	}
+	if len(client.requests) != 6 {
+		t.Errorf("expected 6 requests, got %d", len(client.requests))
+	}
+}
+
+func Test_loadContractItems(t *testing.T) {
+	tests := []struct {
+		name         string
+		client       httpClientTest
+		wantRequests int
+		wantItems    int
+	}{
+		{
+			name:         "no content",
+			client:       httpClientTest{status: http.StatusNoContent},
+			wantRequests: 1,
+			wantItems:    0,
+		},
+		{
+			name: "two pages",
+			client: httpClientTest{
+				data:   `[{"record_id": 1, "type_id": 123, "quantity": 1, "is_included": true}]`,
+				header: http.Header{xPagesHeader: []string{"2"}},
+			},
+			wantRequests: 2,
+			wantItems:    2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := loadContractItems(context.Background(), eveConnector{client: &tt.client}, rand.Int63())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.client.requests) != tt.wantRequests {
+				t.Errorf("expected %d requests, got %d", tt.wantRequests, len(tt.client.requests))
+			}
+			if len(items) != tt.wantItems {
+				t.Errorf("expected %d items, got %d", tt.wantItems, len(items))
+			}
+		})
+	}
+}
+
+func Test_loadContractBids(t *testing.T) {
+	var client = httpClientTest{
+		data:   `[{"bid_id": 1, "amount": 1000000}, {"bid_id": 2, "amount": 2000000}]`,
+		header: http.Header{xPagesHeader: []string{"1"}},
+	}
+	bids, err := loadContractBids(context.Background(), eveConnector{client: &client}, rand.Int63())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the page after the last one is not requested, ESI counts its 404 against the error limit
+	if len(client.requests) != 1 {
+		t.Errorf("expected 1 request, got %d", len(client.requests))
+	}
+	if len(bids) != 2 {
+		t.Errorf("expected 2 bids, got %d", len(bids))
+	}
+}
+
+func Test_getContracts(t *testing.T) {
+	const data = `{"contract_id":152093844,"issuer_id":2112625428,"issuer_corporation_id":98548497,` +
+		`"type":"courier","reward":25000000,"collateral":300000000,"days_to_complete":3,"volume":12000,` +
+		`"start_location_id":60003760,"end_location_id":60008494,"some_new_field":true}`
+	var client = httpClientTest{data: "[" + data + "]"}
+	eve := eveConnector{client: &client}
+	contracts, pages, err := eve.getContracts(context.Background(), strconv.Itoa(rand.Int()), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 1 || len(contracts) != 1 {
+		t.Fatalf("expected one contract on one page, got %d on %d", len(contracts), pages)
+	}
+	want := contract{
+		Id:                  152093844,
+		IssuerId:            2112625428,
+		IssuerCorporationId: 98548497,
+		Type:                courier,
+		Reward:              25000000,
+		Collateral:          300000000,
+		DaysToComplete:      3,
+		Volume:              12000,
+		StartLocationId:     60003760,
+		EndLocationId:       60008494,
+		Raw:                 json.RawMessage(data),
+	}
+	if !reflect.DeepEqual(contracts[0], want) {
+		t.Errorf("getContracts() got = %+v, want %+v", contracts[0], want)
+	}
+}
+
+func Test_pagesExpiry(t *testing.T) {
+	var (
+		now    = time.Now().UTC()
+		expiry = newPagesExpiry()
+	)
+	if next := expiry.next(); !next.IsZero() {
+		t.Errorf("next() = %v, expected zero time", next)
+	}
+	expiry.store("1?1", http.Header{
+		dateHeader:    []string{now.Format(http.TimeFormat)},
+		expiresHeader: []string{now.Add(time.Minute * 5).Format(http.TimeFormat)},
+	})
+	expiry.store("1?2", http.Header{
+		dateHeader:    []string{now.Format(http.TimeFormat)},
+		expiresHeader: []string{now.Add(time.Minute * 2).Format(http.TimeFormat)},
+	})
+	expiry.store("1?3", http.Header{
+		dateHeader:    []string{now.Format(http.TimeFormat)},
+		expiresHeader: []string{now.Add(-time.Minute).Format(http.TimeFormat)},
+	})
+	next := expiry.next()
+	if d := time.Until(next); d < time.Minute || d > time.Minute*2 {
+		t.Errorf("next() = %v, expected in two minutes", next)
+	}
+}
+
+func Test_executeQuery_notModified(t *testing.T) {
+	var (
+		client = httpClientTest{
+			data:   `[{"contract_id": 1}, {"contract_id": 2}]`,
+			header: http.Header{"Etag": []string{`"abc"`}},
+		}
+		eve   = eveConnector{client: &client}
+		cache sync.Map
+		data  []contract
+	)
+	if _, err := eve.executeQuery(context.Background(), apiContracts, regionIdJita, 1, &data, &cache); err != nil {
+		t.Fatal(err)
+	}
+	client.status = http.StatusNotModified
+	client.data = ""
+	data = nil
+	if _, err := eve.executeQuery(context.Background(), apiContracts, regionIdJita, 1, &data, &cache); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.requests[1].header.Get(ifNoneMatchHeader); got != `"abc"` {
+		t.Errorf("unexpected %s header: %s", ifNoneMatchHeader, got)
+	}
+	if len(data) != 2 {
+		t.Errorf("expected 2 contracts from the cached page, got %d", len(data))
+	}
+}
+
+func Test_getItemValue(t *testing.T) {
+	var watch = registryItem{
+		TypeId:        123,
+		Price:         10,
+		OriginalPrice: 1000,
+		MinME:         8,
+		MinTE:         16,
+		MEBonus:       5,
+		TEBonus:       2,
+	}
+	tests := []struct {
+		name string
+		item contractItem
+		want float64
+	}{
+		{
+			name: "minimal research",
+			item: contractItem{TypeId: 123, Runs: 10, Quantity: 1, MaterialEfficiency: 8, TimeEfficiency: 16},
+			want: 100,
+		},
+		{
+			name: "well researched",
+			item: contractItem{TypeId: 123, Runs: 10, Quantity: 1, MaterialEfficiency: 10, TimeEfficiency: 20},
+			want: 114,
+		},
+		{
+			name: "badly researched",
+			item: contractItem{TypeId: 123, Runs: 10, Quantity: 1, MaterialEfficiency: 0, TimeEfficiency: 0},
+			want: 0,
+		},
+		{
+			name: "original",
+			item: contractItem{TypeId: 123, Runs: -1, Quantity: 1, MaterialEfficiency: 10, TimeEfficiency: 16},
+			want: 1100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getItemValue(watch, tt.item); math.Abs(got-tt.want) > 0.000001 {
+				t.Errorf("getItemValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkConstraints(t *testing.T) {
+	var registry = map[int64]registryItem{
+		123: {TypeId: 123, Price: 10, MinRuns: 10, MaxQuantity: 2, TypeName: "Foo"},
+		321: {TypeId: 321, Price: 10, MaxRuns: 5, MinQuantity: 2, TypeName: "Bar"},
+	}
+	tests := []struct {
+		name    string
+		items   []contractItem
+		wantErr bool
+	}{
+		{
+			name: "suitable",
+			items: []contractItem{
+				{TypeId: 123, Runs: 10, Quantity: 2, IsIncluded: true},
+				{TypeId: 321, Runs: 5, Quantity: 1, IsIncluded: true},
+				{TypeId: 321, Runs: 1, Quantity: 1, IsIncluded: true},
+				{TypeId: 111, Runs: 1, Quantity: 100, IsIncluded: true},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "not enough runs",
+			items:   []contractItem{{TypeId: 123, Runs: 9, Quantity: 1, IsIncluded: true}},
+			wantErr: true,
+		},
+		{
+			name:    "too many runs",
+			items:   []contractItem{{TypeId: 321, Runs: 6, Quantity: 2, IsIncluded: true}},
+			wantErr: true,
+		},
+		{
+			name: "too many copies",
+			items: []contractItem{
+				{TypeId: 123, Runs: 10, Quantity: 2, IsIncluded: true},
+				{TypeId: 123, Runs: 10, Quantity: 1, IsIncluded: true},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "not enough copies",
+			items:   []contractItem{{TypeId: 321, Runs: 1, Quantity: 1, IsIncluded: true}},
+			wantErr: true,
+		},
+		{
+			name:    "original has no runs",
+			items:   []contractItem{{TypeId: 123, Runs: -1, Quantity: 1, IsIncluded: true}},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkConstraints(registry, tt.items); (err != nil) != tt.wantErr {
+				t.Errorf("checkConstraints() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const priceHistoryPath = "./history.log"
+
+type (
+	// historyRecord is an evaluated contract with all its items, it tells the real asking prices
+	historyRecord struct {
+		ContractId int64          `json:"contract_id"`
+		Region     string         `json:"region"`
+		Evaluated  time.Time      `json:"evaluated"`
+		Price      float64        `json:"price"`
+		Items      []contractItem `json:"items"`
+	}
+	priceHistory struct {
+		mux  sync.Mutex
+		file *os.File
+	}
+	priceStats struct {
+		count      int
+		min        float64
+		percentile float64
+		median     float64
+	}
+)
+
+func openPriceHistory(path string) (*priceHistory, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// the last record could be truncated if the process was killed while writing
+	if err = terminateLine(f); err != nil {
+		deferWithPrintError(f.Close)
+		return nil, err
+	}
+	return &priceHistory{file: f}, nil
+}
+
+func (h *priceHistory) record(region string, contract contract, items []contractItem) error {
+	if h == nil || contract.Type != itemExchange {
+		// the final price of an auction is unknown
+		return nil
+	}
+	data, err := json.Marshal(historyRecord{
+		ContractId: contract.Id,
+		Region:     region,
+		Evaluated:  time.Now(),
+		Price:      contract.Price,
+		Items:      items,
+	})
+	if err != nil {
+		return err
+	}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	_, err = h.file.Write(append(data, '\n'))
+	return err
+}
+
+func (h *priceHistory) Close() error {
+	if h == nil {
+		return nil
+	}
+	return h.file.Close()
+}
+
+// reads the records of the specified type evaluated since the moment
+func loadPriceHistory(r io.Reader, typeId int64, since time.Time) (records []historyRecord, err error) {
+	err = readLines(r, func(line []byte) error {
+		var rec historyRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			// the last record could be truncated if the process was killed while writing
+			return nil
+		}
+		if rec.Evaluated.Before(since) {
+			return nil
+		}
+		for _, item := range rec.Items {
+			if item.TypeId == typeId {
+				records = append(records, rec)
+				break
+			}
+		}
+		return nil
+	})
+	return records, err
+}
+
+// returns the asking price of a single run of copies or a single original in millions of ISK. Only contracts
+// that offer nothing but the specified type are taken into account, otherwise the price cannot be divided
+func getUnitPrice(rec historyRecord, typeId int64, originals bool) (float64, bool) {
+	var units int64
+	for _, item := range rec.Items {
+		if item.TypeId != typeId || !item.IsIncluded || (item.Runs < 0) != originals {
+			return 0, false
+		}
+		if originals {
+			units += int64(item.Quantity)
+		} else {
+			units += int64(item.Runs) * int64(item.Quantity)
+		}
+	}
+	if units <= 0 || rec.Price <= 0 {
+		return 0, false
+	}
+	return rec.Price / 1000000 / float64(units), true
+}
+
+// nearest-rank percentile of the sorted prices
+func getPercentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+func getPriceStats(records []historyRecord, typeId int64, originals bool, percentile float64) (stats priceStats, ok bool) {
+	var prices []float64
+	for _, rec := range records {
+		if price, ok := getUnitPrice(rec, typeId, originals); ok {
+			prices = append(prices, price)
+		}
+	}
+	if len(prices) == 0 {
+		return stats, false
+	}
+	sort.Float64s(prices)
+	return priceStats{
+		count:      len(prices),
+		min:        prices[0],
+		percentile: getPercentile(prices, percentile),
+		median:     getPercentile(prices, 50),
+	}, true
+}
+
+func statsOperations(state programState) {
+	if state.stats.typeId == 0 {
+		ifErrorFatal(errors.New("type ID is required"))
+	}
+	f, err := os.Open(state.stats.historyPath)
+	ifErrorFatal(err)
+	defer deferWithPrintError(f.Close)
+	since := time.Now().Add(-time.Hour * 24 * time.Duration(state.stats.days))
+	records, err := loadPriceHistory(f, state.stats.typeId, since)
+	ifErrorFatal(err)
+
+	// the registry only gives the name, the command does not create it
+	var name = fmt.Sprintf("type %d", state.stats.typeId)
+	registry, err := readRegistry()
+	ifErrorPrint(err)
+	if t, ok := registry[state.stats.typeId]; ok {
+		name = t.TypeName
+	}
+	fmt.Fprintf(state.output, "%s, %d contracts in %d days\n", name, len(records), state.stats.days)
+	for _, kind := range []struct {
+		title     string
+		unit      string
+		originals bool
+	}{
+		{title: "copies", unit: "run", originals: false},
+		{title: "originals", unit: "item", originals: true},
+	} {
+		stats, ok := getPriceStats(records, state.stats.typeId, kind.originals, state.stats.percentile)
+		if !ok {
+			fmt.Fprintf(state.output, "%s: no data\n", kind.title)
+			continue
+		}
+		fmt.Fprintf(state.output, "%s: %d contracts, min %0.3f M, p%g %0.3f M, median %0.3f M per %s\n",
+			kind.title, stats.count, stats.min, state.stats.percentile, stats.percentile, stats.median, kind.unit)
+	}
+}
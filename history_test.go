@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_getPriceStats(t *testing.T) {
+	var (
+		now     = time.Now()
+		buf     = bytes.NewBuffer([]byte{})
+		enc     = json.NewEncoder(buf)
+		records = []historyRecord{
+			{ContractId: 1, Evaluated: now, Price: 40000000, Items: []contractItem{{TypeId: 123, Runs: 10, Quantity: 1, IsIncluded: true}}},
+			{ContractId: 2, Evaluated: now, Price: 60000000, Items: []contractItem{{TypeId: 123, Runs: 5, Quantity: 2, IsIncluded: true}}},
+			{ContractId: 3, Evaluated: now, Price: 90000000, Items: []contractItem{{TypeId: 123, Runs: 10, Quantity: 1, IsIncluded: true}}},
+			{ContractId: 4, Evaluated: now, Price: 20000000, Items: []contractItem{{TypeId: 123, Runs: 10, Quantity: 1, IsIncluded: true}}},
+			// mixed contract, the price cannot be divided
+			{ContractId: 5, Evaluated: now, Price: 1000000, Items: []contractItem{{TypeId: 123, Runs: 10, Quantity: 1, IsIncluded: true}, {TypeId: 321, Runs: 1, Quantity: 1, IsIncluded: true}}},
+			{ContractId: 6, Evaluated: now, Price: 900000000, Items: []contractItem{{TypeId: 123, Runs: -1, Quantity: 1, IsIncluded: true}}},
+			// out of the time window
+			{ContractId: 7, Evaluated: now.Add(-time.Hour * 48), Price: 1000000, Items: []contractItem{{TypeId: 123, Runs: 10, Quantity: 1, IsIncluded: true}}},
+			{ContractId: 8, Evaluated: now, Price: 1000000, Items: []contractItem{{TypeId: 321, Runs: 10, Quantity: 1, IsIncluded: true}}},
+		}
+	)
+	// a record longer than the default buffer of bufio.Scanner goes first, the rest must be read anyway
+	var bulk = historyRecord{ContractId: 10, Evaluated: now, Price: 1000000}
+	for i := 0; i < 2000; i++ {
+		bulk.Items = append(bulk.Items, contractItem{TypeId: 321, Runs: 10, Quantity: 1, IsIncluded: true})
+	}
+	for _, rec := range append([]historyRecord{bulk}, records...) {
+		if err := enc.Encode(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf.WriteString(`{"contract_id": 9, "evalu`)
+	loaded, err := loadPriceHistory(buf, 123, now.Add(-time.Hour*24))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 6 {
+		t.Fatalf("expected 6 records, got %d", len(loaded))
+	}
+	copies, ok := getPriceStats(loaded, 123, false, 25)
+	if !ok {
+		t.Fatal("no stats for copies")
+	}
+	if want := (priceStats{count: 4, min: 2, percentile: 2, median: 4}); copies != want {
+		t.Errorf("copies stats = %+v, want %+v", copies, want)
+	}
+	originals, ok := getPriceStats(loaded, 123, true, 25)
+	if !ok {
+		t.Fatal("no stats for originals")
+	}
+	if want := (priceStats{count: 1, min: 900, percentile: 900, median: 900}); originals != want {
+		t.Errorf("originals stats = %+v, want %+v", originals, want)
+	}
+}
+
+func Test_openPriceHistory_truncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jitaScan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var path = filepath.Join(dir, "history.log")
+	if err = ioutil.WriteFile(path, []byte(`{"contract_id":1,"region":"10000002","pri`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	history, err := openPriceHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []contractItem{{TypeId: 123, Runs: 10, Quantity: 1, IsIncluded: true}}
+	if err = history.record(regionIdJita, contract{Id: 2, Type: itemExchange, Price: 40000000}, items); err != nil {
+		t.Fatal(err)
+	}
+	if err = history.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := loadPriceHistory(f, 123, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ContractId != 2 {
+		t.Errorf("expected the record after the truncated one, got %v", records)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	errorLimitRemainHeader = "X-Esi-Error-Limit-Remain"
+	errorLimitResetHeader  = "X-Esi-Error-Limit-Reset"
+
+	// how many errors we leave in reserve before ESI bans us
+	errorLimitThreshold = 10
+)
+
+type (
+	// errorLimiter follows the ESI error budget and holds back requests when it is running low
+	errorLimiter struct {
+		mux       sync.Mutex
+		threshold int
+		remain    int
+		reset     time.Time
+		logger    io.Writer
+	}
+	limitedClient struct {
+		client  httpClient
+		limiter *errorLimiter
+	}
+)
+
+func newErrorLimiter(threshold int, logger io.Writer) *errorLimiter {
+	return &errorLimiter{
+		threshold: threshold,
+		remain:    -1,
+		logger:    logger,
+	}
+}
+
+func (l *errorLimiter) update(header http.Header) {
+	remain, err := strconv.Atoi(header.Get(errorLimitRemainHeader))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.Atoi(header.Get(errorLimitResetHeader))
+	if err != nil {
+		return
+	}
+	l.mux.Lock()
+	l.remain = remain
+	l.reset = time.Now().Add(time.Duration(reset) * time.Second)
+	l.mux.Unlock()
+}
+
+// returns how long we have to wait until the error budget is restored
+func (l *errorLimiter) pause() (time.Duration, int) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.remain < 0 || l.remain > l.threshold {
+		return 0, l.remain
+	}
+	return time.Until(l.reset), l.remain
+}
+
+func (l *errorLimiter) wait(ctx context.Context) error {
+	if d, remain := l.pause(); d > 0 {
+		fmt.Fprintf(l.logger, "%s WARNING: ESI error limit is low (%d left), throttling for %s\n", time.Now().Format(time.RFC3339), remain, d.Round(time.Second))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+		}
+	}
+	return nil
+}
+
+func (c limitedClient) Do(req *http.Request) (*http.Response, error) {
+	if err := c.limiter.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err == nil {
+		c.limiter.update(resp.Header)
+	}
+	return resp, err
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_errorLimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    http.Header
+		wantPause bool
+	}{
+		{
+			name:      "no headers",
+			header:    nil,
+			wantPause: false,
+		},
+		{
+			name: "enough budget",
+			header: http.Header{
+				errorLimitRemainHeader: []string{"100"},
+				errorLimitResetHeader:  []string{"30"},
+			},
+			wantPause: false,
+		},
+		{
+			name: "low budget",
+			header: http.Header{
+				errorLimitRemainHeader: []string{"5"},
+				errorLimitResetHeader:  []string{"30"},
+			},
+			wantPause: true,
+		},
+		{
+			name: "budget is restored",
+			header: http.Header{
+				errorLimitRemainHeader: []string{"5"},
+				errorLimitResetHeader:  []string{"0"},
+			},
+			wantPause: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newErrorLimiter(errorLimitThreshold, ioutil.Discard)
+			l.update(tt.header)
+			d, _ := l.pause()
+			if (d > 0) != tt.wantPause {
+				t.Errorf("pause() = %v, wantPause %v", d, tt.wantPause)
+			}
+			if d > 30*time.Second {
+				t.Errorf("pause() = %v, expected no more than reset time", d)
+			}
+		})
+	}
+}
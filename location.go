@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// locationFilter restricts the places where contracts are taken, empty lists are not checked
+type locationFilter struct {
+	stations    map[int64]struct{} // stations and structures
+	systems     map[int64]struct{}
+	home        int64   // solar system
+	maxJumps    int     // from the home system, negative for no limit
+	minSecurity float64 // -1 allows every system
+}
+
+// parses a comma separated list of IDs
+func parseIdList(s string) (map[int64]struct{}, error) {
+	var ids = make(map[int64]struct{})
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("wrong ID %q", f)
+		}
+		ids[id] = struct{}{}
+	}
+	return ids, nil
+}
+
+// the security level as shown in the game, a system above zero is never shown as null security
+func getSecurityLevel(status float64) float64 {
+	if status > 0 && status < 0.05 {
+		return 0.1
+	}
+	return math.Round(status*10) / 10
+}
+
+func (f locationFilter) isEmpty() bool {
+	return len(f.stations) == 0 && len(f.systems) == 0 && (f.home == 0 || f.maxJumps < 0) && f.minSecurity <= -1
+}
+
+// checks the location of the contract, the reason is returned when it does not fit
+func checkLocation(ctx context.Context, eve eveConnector, filter locationFilter, locationId int64) (bool, string, error) {
+	if filter.isEmpty() {
+		return true, "", nil
+	}
+	if _, ok := filter.stations[locationId]; ok {
+		return true, "", nil
+	}
+	systemId, err := eve.getLocationSystem(ctx, locationId)
+	if err == errUnknownLocation {
+		return false, fmt.Sprintf("location %d is unknown", locationId), nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	if len(filter.stations) > 0 || len(filter.systems) > 0 {
+		if _, ok := filter.systems[systemId]; !ok {
+			return false, fmt.Sprintf("location %d is not allowed", locationId), nil
+		}
+	}
+	if filter.minSecurity > -1 {
+		security, err := eve.getSystemSecurity(ctx, systemId)
+		if err == errUnknownLocation {
+			return false, fmt.Sprintf("system %d is unknown", systemId), nil
+		}
+		if err != nil {
+			return false, "", err
+		}
+		if level := getSecurityLevel(security); level < filter.minSecurity {
+			return false, fmt.Sprintf("security of system %d is %0.1f", systemId, level), nil
+		}
+	}
+	if filter.home != 0 && filter.maxJumps >= 0 {
+		jumps, err := eve.getJumps(ctx, filter.home, systemId)
+		if err == errNoRoute {
+			return false, fmt.Sprintf("no route from home to system %d", systemId), nil
+		}
+		if err != nil {
+			return false, "", err
+		}
+		if jumps > filter.maxJumps {
+			return false, fmt.Sprintf("system %d is %d jumps away from home", systemId, jumps), nil
+		}
+	}
+	return true, "", nil
+}
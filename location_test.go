@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_getSecurityLevel(t *testing.T) {
+	tests := []struct {
+		status float64
+		want   float64
+	}{
+		{status: 0.945913, want: 0.9},
+		{status: 0.45, want: 0.5},
+		{status: 0.449, want: 0.4},
+		{status: 0.012, want: 0.1},
+		{status: 0, want: 0},
+		{status: -0.35, want: -0.4},
+	}
+	for _, tt := range tests {
+		if got := getSecurityLevel(tt.status); got != tt.want {
+			t.Errorf("getSecurityLevel(%v) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func Test_checkLocation(t *testing.T) {
+	const (
+		jita    = 60003760
+		amarr   = 60008494
+		tama    = 60015143
+		citadel = 1022734985679
+	)
+	client := &pathClientTest{data: map[string]string{
+		"/latest/universe/stations/60003760": `{"station_id":60003760,"system_id":30000142}`,
+		"/latest/universe/stations/60008494": `{"station_id":60008494,"system_id":30002187}`,
+		"/latest/universe/stations/60015143": `{"station_id":60015143,"system_id":30002813}`,
+		"/latest/universe/stations/60000002": `{"station_id":60000002,"system_id":30000001}`,
+		"/latest/universe/systems/30000142":  `{"system_id":30000142,"security_status":0.945913}`,
+		"/latest/universe/systems/30002187":  `{"system_id":30002187,"security_status":1.0}`,
+		"/latest/universe/systems/30002813":  `{"system_id":30002813,"security_status":0.3}`,
+		"/latest/route/30000142/30002187":    `[30000142,30000144,30000139,30002187]`,
+		"/latest/route/30000142/30002813":    `[30000142,30000138,30002813]`,
+	}}
+	eve := eveConnector{client: client, universe: newUniverseCache()}
+	tests := []struct {
+		name     string
+		filter   locationFilter
+		location int64
+		want     bool
+	}{
+		{
+			name:     "no filter",
+			filter:   locationFilter{maxJumps: -1, minSecurity: -1},
+			location: citadel,
+			want:     true,
+		},
+		{
+			name:     "allowed structure",
+			filter:   locationFilter{stations: map[int64]struct{}{citadel: {}}, maxJumps: -1, minSecurity: -1},
+			location: citadel,
+			want:     true,
+		},
+		{
+			name:     "unknown structure",
+			filter:   locationFilter{maxJumps: -1, minSecurity: 0.5},
+			location: citadel,
+			want:     false,
+		},
+		{
+			name:     "unknown station",
+			filter:   locationFilter{maxJumps: -1, minSecurity: 0.5},
+			location: 60000001,
+			want:     false,
+		},
+		{
+			name:     "unknown system",
+			filter:   locationFilter{maxJumps: -1, minSecurity: 0.5},
+			location: 60000002,
+			want:     false,
+		},
+		{
+			name:     "allowed system",
+			filter:   locationFilter{systems: map[int64]struct{}{30000142: {}}, maxJumps: -1, minSecurity: -1},
+			location: jita,
+			want:     true,
+		},
+		{
+			name:     "not allowed system",
+			filter:   locationFilter{stations: map[int64]struct{}{jita: {}}, systems: map[int64]struct{}{30000142: {}}, maxJumps: -1, minSecurity: -1},
+			location: amarr,
+			want:     false,
+		},
+		{
+			name:     "lowsec",
+			filter:   locationFilter{maxJumps: -1, minSecurity: 0.5},
+			location: tama,
+			want:     false,
+		},
+		{
+			name:     "no route from home",
+			filter:   locationFilter{home: 30045328, maxJumps: 10, minSecurity: -1},
+			location: jita,
+			want:     false,
+		},
+		{
+			name:     "near home",
+			filter:   locationFilter{home: 30000142, maxJumps: 2, minSecurity: 0.5},
+			location: jita,
+			want:     true,
+		},
+		{
+			name:     "too far from home",
+			filter:   locationFilter{home: 30000142, maxJumps: 2, minSecurity: 0.5},
+			location: amarr,
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason, err := checkLocation(context.Background(), eve, tt.filter, tt.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("checkLocation() = %v (%s), want %v", got, reason, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/gordonklaus/portaudio"
@@ -8,36 +10,100 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 )
 
 const (
-	paramVerbose = "verbose"
-	paramRegion  = "region"
-	paramShow    = "show"
-	paramTypes   = "types"
-	paramAdd     = "add"
+	paramVerbose  = "verbose"
+	paramRegion   = "region"
+	paramWorkers  = "workers"
+	paramRetries  = "retries"
+	paramMaxDelay = "retry-max-delay"
+	paramEsiUrl   = "esi-url"
+	paramSource   = "datasource"
+	paramStore    = "store"
+	paramKeep     = "retention"
+	paramAuction  = "auction-window"
+	paramCourier  = "courier"
+	paramPerJump  = "courier-per-jump"
+	paramPerM3    = "courier-per-m3"
+	paramCollat   = "courier-collateral"
+	paramStations = "stations"
+	paramSystems  = "systems"
+	paramHome     = "home"
+	paramJumps    = "max-jumps"
+	paramSecurity = "min-security"
+	paramDays     = "days"
+	paramHistory  = "history"
+	paramType     = "type"
+	paramPercent  = "percentile"
+	paramShow     = "show"
+	paramTypes    = "types"
+	paramAdd      = "add"
+	paramSell     = "sell"
+	paramNotify   = "notify"
+	paramAlerts   = "alerts-file"
+
+	// ESI does not update the cache at the exact moment specified in Expires
+	expiryMargin    = time.Second
+	minTickInterval = time.Second * 5
 
 	commandMonitoring = "monitoring"
 	commandRegistry   = "registry"
+	commandReport     = "report"
+	commandStats      = "stats"
 )
 
 type (
 	monitoringState struct {
-		db      *memdb.MemDB
-		verbose bool
-		region  string
-		logger  io.Writer
+		db        *memdb.MemDB
+		seen      *seenContractsLog
+		storePath string
+		history   *priceHistory
+		histPath  string
+		retention time.Duration
+		started   time.Time
+		auctions  *auctionChecks
+		window    time.Duration
+		courier   courierRules
+		locations locationFilter
+		issuers   issuerFilter
+		stations  string
+		systems   string
+		notify    string
+		alerts    string
+		webhooks  []webhookConfig
+		verbose   bool
+		region    string
+		regions   []string
+		workers   int
+		logger    io.Writer
 	}
 	registryState struct {
 		showRegistry bool
 		showTypes    bool
 		addItem      string
+		sellItem     string
 		// registry     map[int64]registryItem
 	}
+	reportState struct {
+		storePath string
+		days      int
+	}
+	statsState struct {
+		historyPath string
+		typeId      int64
+		days        int
+		percentile  float64
+	}
 	programState struct {
 		monitoring monitoringState
 		registry   registryState
+		report     reportState
+		stats      statsState
 		eve        eveConnector
 		output     io.Writer
 	}
@@ -68,16 +134,51 @@ func ifErrorFatal2(_ int, err error) {
 
 func (state *programState) init() map[string]*flag.FlagSet {
 	state.output = os.Stdout
+	config := loadConfig()
+
+	state.monitoring.issuers = newIssuerFilter(config)
+	state.monitoring.webhooks = config.Webhooks
 
 	fsMonitoring := flag.NewFlagSet(commandMonitoring, flag.PanicOnError)
 	fsMonitoring.BoolVar(&state.monitoring.verbose, paramVerbose, false, "show information messages")
-	fsMonitoring.StringVar(&state.monitoring.region, paramRegion, regionIdJita, "select a region to search for contracts")
+	fsMonitoring.StringVar(&state.monitoring.region, paramRegion, regionIdJita, "comma separated list of region IDs or names to search for contracts")
+	fsMonitoring.IntVar(&state.monitoring.workers, paramWorkers, 4, "number of contract pages loaded in parallel")
+	fsMonitoring.IntVar(&state.eve.retry.attempts, paramRetries, 4, "number of attempts for a failed ESI request")
+	fsMonitoring.DurationVar(&state.eve.retry.maxDelay, paramMaxDelay, time.Second*10, "maximum delay between attempts")
+	fsMonitoring.StringVar(&state.eve.baseUrl, paramEsiUrl, config.EsiUrl, "ESI base URL, e.g. a caching proxy")
+	fsMonitoring.StringVar(&state.eve.datasource, paramSource, config.Datasource, "ESI datasource: tranquility or singularity")
+	fsMonitoring.StringVar(&state.monitoring.storePath, paramStore, seenContractsPath, "file to keep seen contracts between restarts, empty to keep them in memory only")
+	fsMonitoring.StringVar(&state.monitoring.histPath, paramHistory, priceHistoryPath, "file to record evaluated contracts, empty to disable")
+	fsMonitoring.DurationVar(&state.monitoring.retention, paramKeep, time.Hour*24*30, "how long to keep sold contracts with watched items for reports")
+	fsMonitoring.DurationVar(&state.monitoring.window, paramAuction, time.Hour, "check the bids of the auctions ending within this time")
+	fsMonitoring.StringVar(&state.monitoring.stations, paramStations, "", "comma separated list of station or structure IDs where contracts are allowed")
+	fsMonitoring.StringVar(&state.monitoring.systems, paramSystems, "", "comma separated list of solar system IDs where contracts are allowed")
+	fsMonitoring.Int64Var(&state.monitoring.locations.home, paramHome, 0, "home solar system ID")
+	fsMonitoring.IntVar(&state.monitoring.locations.maxJumps, paramJumps, -1, "maximum jumps from the home system, negative for no limit")
+	fsMonitoring.Float64Var(&state.monitoring.locations.minSecurity, paramSecurity, -1, "minimum security level of the solar system")
+	fsMonitoring.StringVar(&state.monitoring.notify, paramNotify, "console,sound", "comma separated list of notifiers: console, sound, file, desktop, webhook")
+	fsMonitoring.StringVar(&state.monitoring.alerts, paramAlerts, "./alerts.log", "file for the file notifier")
+	fsMonitoring.BoolVar(&state.monitoring.courier.enabled, paramCourier, false, "search for courier contracts")
+	fsMonitoring.Float64Var(&state.monitoring.courier.minPerJump, paramPerJump, 0, "minimum reward of a courier contract per jump in millions of ISK")
+	fsMonitoring.Float64Var(&state.monitoring.courier.minPerM3, paramPerM3, 0, "minimum reward of a courier contract per cubic meter in ISK")
+	fsMonitoring.Float64Var(&state.monitoring.courier.maxCollateral, paramCollat, 0, "maximum collateral of a courier contract in millions of ISK, zero for no limit")
 
 	fsRegistry := flag.NewFlagSet(commandRegistry, flag.PanicOnError)
 	fsRegistry.BoolVar(&state.registry.showRegistry, paramShow, false, "show a list of items registered for monitoring")
 	fsRegistry.StringVar(&state.registry.addItem, paramAdd, "", "add item to monitoring list")
+	fsRegistry.StringVar(&state.registry.sellItem, paramSell, "", "set the price we accept to sell the item to those who want to buy it")
 	fsRegistry.BoolVar(&state.registry.showTypes, paramTypes, false, "show all eve item types")
 
+	fsReport := flag.NewFlagSet(commandReport, flag.PanicOnError)
+	fsReport.StringVar(&state.report.storePath, paramStore, seenContractsPath, "file with seen contracts")
+	fsReport.IntVar(&state.report.days, paramDays, 30, "report on contracts that disappeared during this number of days")
+
+	fsStats := flag.NewFlagSet(commandStats, flag.PanicOnError)
+	fsStats.StringVar(&state.stats.historyPath, paramHistory, priceHistoryPath, "file with evaluated contracts")
+	fsStats.Int64Var(&state.stats.typeId, paramType, 0, "type ID to show asking prices for")
+	fsStats.IntVar(&state.stats.days, paramDays, 30, "time window in days")
+	fsStats.Float64Var(&state.stats.percentile, paramPercent, 25, "percentile of the asking price to show")
+
 	var err error
 	if state.monitoring.db, err = connectToDatabase(); err != nil {
 		panic(err)
@@ -87,88 +188,294 @@ func (state *programState) init() map[string]*flag.FlagSet {
 	return map[string]*flag.FlagSet{
 		commandMonitoring: fsMonitoring,
 		commandRegistry:   fsRegistry,
+		commandReport:     fsReport,
+		commandStats:      fsStats,
+	}
+}
+
+// contracts whose items could not be loaded are checked again until it succeeds or the contract expires
+func retryPendingContracts(ctx context.Context, state programState, region string, registry map[int64]registryItem, chSignal chan<- registrySignal) {
+	var waiting = 0
+	for _, contract := range getPendingContractsDB(state.monitoring.db, region) {
+		if contract.DateExpired.Before(time.Now()) {
+			removePendingContractDB(state.monitoring.db, contract)
+			continue
+		}
+		if ctx.Err() != nil {
+			waiting++
+			continue
+		}
+		items, err := checkContractByType(ctx, state, region, contract.contract, registry, chSignal)
+		if err != nil {
+			if ctx.Err() == nil {
+				ifErrorPrint(err)
+			}
+			waiting++
+			continue
+		}
+		removePendingContractDB(state.monitoring.db, contract)
+		storeCheckedContract(state.monitoring, contract.Id, getWatchedItems(registry, items))
+		if items != nil {
+			// the filtered contracts are not loaded
+			ifErrorPrint(state.monitoring.history.record(region, contract.contract, items))
+		}
+	}
+	fmt.Fprintf(state.monitoring.logger, "%d contracts from region %s are waiting for retry\n", waiting, getRegionName(region))
+}
+
+// the trade contracts are of no interest without the registry, the couriers are searched only on demand
+func isMonitoredContract(state monitoringState, registry map[int64]registryItem, contract contract) bool {
+	if isPublicCourierContract(contract) {
+		return state.courier.enabled
+	}
+	return len(registry) > 0 && isPublicTradeContract(contract)
+}
+
+// checks the contract against the filters of the monitoring, the reason of the skip goes to the log
+func checkFilters(ctx context.Context, state programState, contract contract) (bool, error) {
+	ok, reason := checkIssuer(state.monitoring.issuers, contract)
+	if ok {
+		// the items are taken or the cargo is picked up at the start location
+		var err error
+		if ok, reason, err = checkLocation(ctx, state.eve, state.monitoring.locations, contract.StartLocationId); err != nil {
+			return false, err
+		}
+	}
+	if !ok {
+		fmt.Fprintf(state.monitoring.logger, "contract %d skipped: %s\n", contract.Id, reason)
+	}
+	return ok, nil
+}
+
+// courier contracts have no items, they are checked by the route and the reward
+func checkContractByType(ctx context.Context, state programState, region string, contract contract, registry map[int64]registryItem, chSignal chan<- registrySignal) ([]contractItem, error) {
+	if ok, err := checkFilters(ctx, state, contract); !ok || err != nil {
+		return nil, err
+	}
+	if contract.Type == courier {
+		return nil, monCheckCourier(ctx, region, contract, state.eve, state.monitoring.courier, chSignal, state.monitoring.logger)
 	}
+	return monCheckContract(ctx, region, contract, state.eve, registry, chSignal, state.monitoring.logger)
 }
 
-// one-time execution of the tracking process
-func monitorTick(state programState, registry map[int64]registryItem, checkContract bool, chSignal chan<- registrySignal) {
+// remembers the watched items of the checked contract and writes it to the log
+func storeCheckedContract(state monitoringState, id int64, watched []contractItem) {
+	c, ok := getContractDB(state.db, id)
+	if !ok {
+		return
+	}
+	if len(watched) > 0 {
+		c.Watched = watched
+		updateContractDB(state.db, c)
+	}
+	ifErrorPrint(state.seen.append(c))
+}
+
+// one-time execution of the tracking process, returns the number of pages that failed to load
+func monitorTick(ctx context.Context, state programState, region string, registry map[int64]registryItem, checkContract bool, chSignal chan<- registrySignal) int {
 	var (
-		conCh = make(chan contract, 10)
-		errCh = make(chan error, 10)
+		conCh   = make(chan contract, 10)
+		errCh   = make(chan error, 10)
+		errDone = make(chan struct{})
+		failed  = 0
+		found   = make(map[int64]struct{})
 	)
-	go loadAllContracts(state.eve, state.monitoring.region, state.monitoring.logger, conCh, errCh)
+	if checkContract {
+		retryPendingContracts(ctx, state, region, registry, chSignal)
+	}
+	go loadAllContracts(ctx, state.eve, region, state.monitoring.workers, state.monitoring.logger, conCh, errCh)
 	go func() {
+		defer close(errDone)
 		fmt.Fprintln(state.monitoring.logger, "started error reader thread")
 		for err := range errCh {
-			ifErrorPrint(err)
+			failed++
+			// errors of the aborted requests do not matter during shutdown
+			if ctx.Err() == nil {
+				ifErrorPrint(err)
+			}
 		}
 		fmt.Fprintln(state.monitoring.logger, "closed error reader thread")
 	}()
 	fmt.Fprintln(state.monitoring.logger, "started contract reader thread")
 	for contract := range conCh {
-		if isPublicItemExchangeContract(contract) {
-			isNew := isNewlyCreatedContractCheckDB(state.monitoring.db, contract)
-			if isNew && checkContract {
-				fmt.Fprintf(state.monitoring.logger, "got newly created: %d\n", contract.Id)
+		if isMonitoredContract(state.monitoring, registry, contract) {
+			found[contract.Id] = struct{}{}
+			isNew := isNewlyCreatedContractCheckDB(state.monitoring.db, region, contract)
+			if isNew && !checkContract {
+				storeCheckedContract(state.monitoring, contract.Id, nil)
+			}
+			if isNew && checkContract && ctx.Err() == nil {
+				fmt.Fprintf(state.monitoring.logger, "got newly created in region %s: %d\n", getRegionName(region), contract.Id)
 				if contract.Title != "" {
 					fmt.Fprintln(state.monitoring.logger, contract.Title)
 				}
-				monCheckContract(contract, state.eve, registry, chSignal, state.monitoring.logger)
+				if len(contract.Raw) > 0 {
+					fmt.Fprintf(state.monitoring.logger, "%s\n", contract.Raw)
+				}
+				items, err := checkContractByType(ctx, state, region, contract, registry, chSignal)
+				if err != nil {
+					if ctx.Err() == nil {
+						ifErrorPrint(err)
+					}
+					addPendingContractDB(state.monitoring.db, region, contract)
+					continue
+				}
+				// only checked contracts are stored, the rest will be checked again after restart
+				storeCheckedContract(state.monitoring, contract.Id, getWatchedItems(registry, items))
+				if items != nil {
+					ifErrorPrint(state.monitoring.history.record(region, contract, items))
+				}
+			}
+			if checkContract && ctx.Err() == nil {
+				checkEndingAuction(ctx, state, region, contract, registry, chSignal)
 			}
 		}
 	}
 	fmt.Fprintln(state.monitoring.logger, "closed contract reader thread")
+	<-errDone
+	// contracts that disappeared from the full list were accepted or withdrawn
+	if failed == 0 && ctx.Err() == nil {
+		disappeared, reappeared, removed := updateLifecycleDB(state.monitoring.db, region, found, time.Now(), state.monitoring.started)
+		for _, c := range disappeared {
+			fmt.Fprintf(state.monitoring.logger, "contract %d with watched items disappeared from region %s\n", c.Id, getRegionName(region))
+			ifErrorPrint(state.monitoring.seen.append(c))
+		}
+		// the log must not keep them as sold
+		for _, c := range reappeared {
+			fmt.Fprintf(state.monitoring.logger, "contract %d with watched items reappeared in region %s\n", c.Id, getRegionName(region))
+			ifErrorPrint(state.monitoring.seen.append(c))
+		}
+		fmt.Fprintf(state.monitoring.logger, "%d disappeared contracts removed from region %s\n", removed, getRegionName(region))
+	}
+	return failed
 }
 
-func alerter(w io.Writer, registry map[int64]registryItem, chSignal <-chan registrySignal) {
-	var lastTime = time.Now()
-	for sig := range chSignal {
-		fmt.Fprintln(w, "*********************************")
-		fmt.Fprintln(w, sig.contract.Title)
-		fmt.Fprintf(w, "Price: %0.3f M\n", sig.contract.Price/1000000)
-		for _, s := range sig.items {
-			fmt.Fprintln(w, "---------------------------------")
-			if t, ok := registry[s.TypeId]; ok {
-				fmt.Fprintf(w, "Item: %s\n", t.TypeName)
-			}
-			if s.Runs > 0 {
-				fmt.Fprintf(w, "Quantity: %d\nRuns: %d\n", s.Quantity, s.Runs)
-			} else {
-				fmt.Fprintf(w, "Quantity: %d\nORIGINAL\n", s.Quantity)
+// removes expired contracts and compacts the log when it has too many outdated records
+func pruneContracts(state monitoringState) {
+	removed := removeExpiredContractsDB(state.db, time.Now(), state.retention)
+	state.auctions.forgetRemoved(state.db)
+	size := countContractsDB(state.db)
+	fmt.Fprintf(state.logger, "%d expired contracts removed, %d contracts in the table\n", removed, size)
+	if state.seen.isOutdated(size) {
+		fmt.Fprintln(state.logger, "compacting the seen contracts log")
+		ifErrorPrint(state.seen.compact(getCheckedContractsDB(state.db)))
+	}
+}
+
+// the next tick is scheduled for the moment when ESI refreshes its cache, there is no point in asking earlier
+func nextTickDelay(eve eveConnector) time.Duration {
+	var delay = minTickInterval
+	if eve.expires != nil {
+		if next := eve.expires.next(); !next.IsZero() {
+			if d := time.Until(next) + expiryMargin; d > delay {
+				delay = d
 			}
 		}
-		fmt.Fprintln(w, "*********************************")
-		fmt.Fprintln(w, "")
-		if lastTime.Add(time.Second * 20).Before(time.Now()) {
-			ifErrorPrint(warning())
-		}
 	}
+	return delay
 }
 
-func startMonitoring(state programState) {
+func startMonitoring(ctx context.Context, state programState) {
 	fmt.Println("initialization...")
 	registry := loadRegistry()
-	if len(registry) == 0 {
+	if len(registry) == 0 && !state.monitoring.courier.enabled {
 		fmt.Fprintln(os.Stderr, "registry is empty")
 		os.Exit(2)
 	}
-	// audio system initialization required
-	ifErrorFatal(portaudio.Initialize())
-	defer deferWithPrintError(portaudio.Terminate)
+	enabled, err := parseNotifiers(state.monitoring.notify)
+	ifErrorFatal(err)
+	var notifiers []notifier
+	if enabled[notifyConsole] {
+		notifiers = append(notifiers, consoleNotifier{w: os.Stdout, registry: registry})
+	}
+	if enabled[notifySound] {
+		// audio system initialization required
+		ifErrorFatal(portaudio.Initialize())
+		defer deferWithPrintError(portaudio.Terminate)
+		notifiers = append(notifiers, soundNotifier{started: time.Now()})
+	}
+	if enabled[notifyFile] {
+		fileNotifier, err := openFileNotifier(state.monitoring.alerts, registry)
+		ifErrorFatal(err)
+		defer deferWithPrintError(fileNotifier.Close)
+		notifiers = append(notifiers, fileNotifier)
+	}
+	if enabled[notifyDesktop] {
+		desktop, err := newDesktopNotifier()
+		ifErrorFatal(err)
+		notifiers = append(notifiers, desktop)
+	}
+	if enabled[notifyWebhook] {
+		if len(state.monitoring.webhooks) == 0 {
+			ifErrorFatal(errors.New("no webhooks in the config file"))
+		}
+		// every receiver is a separate notifier with its own rate limit
+		for i, config := range state.monitoring.webhooks {
+			webhook, err := newWebhookNotifier(config, registry, state.eve.retry)
+			if err != nil {
+				ifErrorFatal(fmt.Errorf("webhook #%d in the config file: %s", i+1, err))
+			}
+			notifiers = append(notifiers, webhook)
+		}
+	}
 
 	var (
-		checkContracts = false
+		// regions known from the previous runs are checked from the very first tick,
+		// the rest are scanned once to remember contracts that already exist
+		checkContracts = make(map[string]bool)
 		chSignal       = make(chan registrySignal, 10)
+		alerterDone    = make(chan struct{})
 	)
-	defer close(chSignal)
-	go alerter(os.Stdout, registry, chSignal)
+	for _, region := range state.monitoring.regions {
+		checkContracts[region] = isRegionKnownDB(state.monitoring.db, region)
+	}
+	go func() {
+		defer close(alerterDone)
+		alerter(ctx, notifiers, chSignal)
+	}()
+	defer func() {
+		close(chSignal)
+		<-alerterDone
+	}()
 	for {
-		<-time.After(time.Second * 5)
-		monitorTick(state, registry, checkContracts, chSignal)
-		if !checkContracts {
-			checkContracts = true
+		delay := nextTickDelay(state.eve)
+		fmt.Fprintf(state.monitoring.logger, "next tick in %s\n", delay.Round(time.Second))
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stdout, "shutting down...")
+			return
+		case <-time.After(delay):
+		}
+		// every region is scanned concurrently and has its own seen contracts in the database
+		var (
+			wg     sync.WaitGroup
+			failed = make([]int, len(state.monitoring.regions))
+		)
+		for i, region := range state.monitoring.regions {
+			wg.Add(1)
+			go func(i int, region string, checkContract bool) {
+				defer wg.Done()
+				failed[i] = monitorTick(ctx, state, region, registry, checkContract, chSignal)
+			}(i, region, checkContracts[region])
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			continue
+		}
+		pruneContracts(state.monitoring)
+		var started = false
+		for i, region := range state.monitoring.regions {
+			// the region is checked only after a complete scan, otherwise the missed contracts would look new
+			if !checkContracts[region] && failed[i] == 0 {
+				checkContracts[region] = true
+				started = true
+			}
+		}
+		if started {
 			fmt.Fprintln(os.Stdout, "now we can start monitoring")
-			ifErrorPrint(warning())
+			if enabled[notifySound] {
+				ifErrorPrint(warning())
+			}
 		}
 	}
 }
@@ -190,10 +497,19 @@ func registryOperations(state programState) {
 		ifErrorFatal(err)
 		ifErrorFatal2(state.output.Write([]byte(doneStr)))
 	}
+	if state.registry.sellItem != "" {
+		var (
+			err     error
+			doneStr string
+		)
+		registry, doneStr, err = addSellToRegistry(registry, allTypes, state.registry.sellItem)
+		ifErrorFatal(err)
+		ifErrorFatal2(state.output.Write([]byte(doneStr)))
+	}
 	if state.registry.showRegistry {
 		fmt.Fprintln(state.output, "monitoring list:")
 		for _, i := range registry {
-			fmt.Fprintf(state.output, "%s [%0.3f]\n", getItemName(allTypes, i.TypeId), i.Price)
+			fmt.Fprintf(state.output, "%s [%s]\n", getItemName(allTypes, i.TypeId), describeRegistryItem(i))
 		}
 	}
 }
@@ -220,16 +536,56 @@ func main() {
 	} else {
 		flagSets[commandMonitoring].Usage()
 		flagSets[commandRegistry].Usage()
+		flagSets[commandReport].Usage()
+		flagSets[commandStats].Usage()
 	}
 
 	if command == commandMonitoring {
 		if state.monitoring.verbose {
 			state.monitoring.logger = os.Stdout
 		}
-		startMonitoring(state)
+		// the pause can be long, so it is reported even without the verbose flag
+		state.eve.limiter = newErrorLimiter(errorLimitThreshold, os.Stderr)
+		state.eve.expires = newPagesExpiry()
+		state.monitoring.auctions = newAuctionChecks()
+		state.monitoring.started = time.Now()
+		state.eve.universe = newUniverseCache()
+		var err error
+		state.monitoring.regions, err = parseRegions(state.monitoring.region)
+		ifErrorFatal(err)
+		state.monitoring.locations.stations, err = parseIdList(state.monitoring.stations)
+		ifErrorFatal(err)
+		state.monitoring.locations.systems, err = parseIdList(state.monitoring.systems)
+		ifErrorFatal(err)
+		for _, region := range state.monitoring.regions {
+			fmt.Fprintf(state.monitoring.logger, "monitoring region %s (%s)\n", getRegionName(region), region)
+		}
+		if state.monitoring.storePath != "" {
+			state.monitoring.seen, err = openSeenContractsLog(state.monitoring.storePath)
+			ifErrorFatal(err)
+			defer deferWithPrintError(state.monitoring.seen.Close)
+			count, err := state.monitoring.seen.replay(state.monitoring.db)
+			ifErrorFatal(err)
+			fmt.Fprintf(state.monitoring.logger, "%d seen contracts restored from %s\n", count, state.monitoring.storePath)
+		}
+		if state.monitoring.histPath != "" {
+			state.monitoring.history, err = openPriceHistory(state.monitoring.histPath)
+			ifErrorFatal(err)
+			defer deferWithPrintError(state.monitoring.history.Close)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		startMonitoring(ctx, state)
+		return
 	}
 	if command == commandRegistry {
 		registryOperations(state)
 	}
+	if command == commandReport {
+		reportOperations(state)
+	}
+	if command == commandStats {
+		statsOperations(state)
+	}
 	os.Exit(1)
 }
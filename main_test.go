@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"math/rand"
 	"testing"
 )
@@ -186,9 +187,10 @@ func Test_checkSuitable(t *testing.T) {
 			args: args{
 				registry: map[int64]registryItem{
 					123: {
-						TypeId:   123,
-						Price:    40.99,
-						TypeName: "Foo",
+						TypeId:        123,
+						Price:         40.99,
+						OriginalPrice: 8600,
+						TypeName:      "Foo",
 					},
 					321: {
 						TypeId:   321,
@@ -198,7 +200,7 @@ func Test_checkSuitable(t *testing.T) {
 				},
 				contract: contract{
 					Id:             144,
-					Price:          18900000000, // 1880.98,
+					Price:          18900000000, // 2*8600 + 1799
 					ForCorporation: false,
 					Title:          "Test",
 					Type:           itemExchange,
@@ -224,6 +226,100 @@ func Test_checkSuitable(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "original too expensive",
+			args: args{
+				registry: map[int64]registryItem{
+					123: {
+						TypeId:        123,
+						Price:         40.99,
+						OriginalPrice: 8500,
+						TypeName:      "Foo",
+					},
+				},
+				contract: contract{
+					Id:             144,
+					Price:          8600000000,
+					ForCorporation: false,
+					Title:          "Test",
+					Type:           itemExchange,
+				},
+				items: []contractItem{
+					{
+						RecordId:        2,
+						IsBlueprintCopy: false,
+						IsIncluded:      true,
+						Runs:            -1,
+						Quantity:        1,
+						TypeId:          123,
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "copies ignored",
+			args: args{
+				registry: map[int64]registryItem{
+					123: {
+						TypeId:       123,
+						Price:        40.99,
+						IgnoreCopies: true,
+						TypeName:     "Foo",
+					},
+				},
+				contract: contract{
+					Id:             144,
+					Price:          1000000,
+					ForCorporation: false,
+					Title:          "Test",
+					Type:           itemExchange,
+				},
+				items: []contractItem{
+					{
+						RecordId:        2,
+						IsBlueprintCopy: true,
+						IsIncluded:      true,
+						Runs:            10,
+						Quantity:        1,
+						TypeId:          123,
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "originals ignored",
+			args: args{
+				registry: map[int64]registryItem{
+					123: {
+						TypeId:          123,
+						Price:           40.99,
+						OriginalPrice:   8600,
+						IgnoreOriginals: true,
+						TypeName:        "Foo",
+					},
+				},
+				contract: contract{
+					Id:             144,
+					Price:          1000000,
+					ForCorporation: false,
+					Title:          "Test",
+					Type:           itemExchange,
+				},
+				items: []contractItem{
+					{
+						RecordId:        2,
+						IsBlueprintCopy: false,
+						IsIncluded:      true,
+						Runs:            -1,
+						Quantity:        1,
+						TypeId:          123,
+					},
+				},
+			},
+			want: false,
+		},
 		{
 			name: "excluded",
 			args: args{
@@ -267,6 +363,65 @@ func Test_checkSuitable(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "auction buyout",
+			args: args{
+				registry: map[int64]registryItem{
+					123: {
+						TypeId:   123,
+						Price:    40.99,
+						TypeName: "Foo",
+					},
+				},
+				contract: contract{
+					Id:     144,
+					Price:  1000000,
+					Buyout: 81000000,
+					Title:  "Test",
+					Type:   auction,
+				},
+				items: []contractItem{
+					{
+						RecordId:        2,
+						IsBlueprintCopy: true,
+						IsIncluded:      true,
+						Runs:            2,
+						Quantity:        1,
+						TypeId:          123,
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "auction without buyout",
+			args: args{
+				registry: map[int64]registryItem{
+					123: {
+						TypeId:   123,
+						Price:    40.99,
+						TypeName: "Foo",
+					},
+				},
+				contract: contract{
+					Id:    144,
+					Price: 1000000,
+					Title: "Test",
+					Type:  auction,
+				},
+				items: []contractItem{
+					{
+						RecordId:        2,
+						IsBlueprintCopy: true,
+						IsIncluded:      true,
+						Runs:            2,
+						Quantity:        1,
+						TypeId:          123,
+					},
+				},
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -278,6 +433,86 @@ func Test_checkSuitable(t *testing.T) {
 	}
 }
 
+func Test_checkWanted(t *testing.T) {
+	registry := map[int64]registryItem{
+		123: {
+			TypeId:            123,
+			Price:             40.99,
+			SellPrice:         50,
+			SellOriginalPrice: 9000,
+			TypeName:          "Foo",
+		},
+		321: {
+			TypeId:   321,
+			Price:    899.50,
+			TypeName: "Bar",
+		},
+	}
+	wanted := func(typeId int64, runs, quantity int32) contractItem {
+		return contractItem{IsIncluded: false, Runs: runs, Quantity: quantity, TypeId: typeId}
+	}
+	tests := []struct {
+		name     string
+		contract contract
+		items    []contractItem
+		want     bool
+	}{
+		{
+			name:     "copies wanted",
+			contract: contract{Id: 1, Reward: 500000000, Type: itemExchange},
+			items:    []contractItem{wanted(123, 5, 2)},
+			want:     true,
+		},
+		{
+			name:     "original wanted",
+			contract: contract{Id: 2, Reward: 9000000000, Type: itemExchange},
+			items:    []contractItem{wanted(123, -1, 1)},
+			want:     true,
+		},
+		{
+			name:     "reward too small",
+			contract: contract{Id: 3, Reward: 499000000, Type: itemExchange},
+			items:    []contractItem{wanted(123, 5, 2)},
+			want:     false,
+		},
+		{
+			name:     "price is taken from the reward",
+			contract: contract{Id: 4, Price: 100000000, Reward: 550000000, Type: itemExchange},
+			items:    []contractItem{wanted(123, 5, 2)},
+			want:     false,
+		},
+		{
+			name:     "not for sale",
+			contract: contract{Id: 5, Reward: 900000000000, Type: itemExchange},
+			items:    []contractItem{wanted(321, 1, 1)},
+			want:     false,
+		},
+		{
+			name:     "unknown type",
+			contract: contract{Id: 6, Reward: 900000000000, Type: itemExchange},
+			items:    []contractItem{wanted(123, 5, 2), wanted(111, 1, 1)},
+			want:     false,
+		},
+		{
+			name:     "items offered",
+			contract: contract{Id: 7, Reward: 900000000000, Type: itemExchange},
+			items: []contractItem{
+				wanted(123, 5, 2),
+				{IsIncluded: true, Runs: 1, Quantity: 1, TypeId: 321},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkWanted(registry, tt.contract, tt.items)
+			if got != tt.want {
+				t.Errorf("checkWanted() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Benchmark_checkSuitable(b *testing.B) {
 	type args struct {
 		registry map[int64]registryItem
@@ -353,8 +588,14 @@ func Test_alerter(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var w = bytes.NewBuffer([]byte{})
-			go alerter(w, tt.args.registry, tt.args.chSignal)
+			var (
+				w    = bytes.NewBuffer([]byte{})
+				done = make(chan struct{})
+			)
+			go func() {
+				defer close(done)
+				alerter(context.Background(), []notifier{consoleNotifier{w: w, registry: tt.args.registry}}, tt.args.chSignal)
+			}()
 			for i := 0; i < 5; i++ {
 				tt.args.chSignal <- registrySignal{
 					contract: contract{
@@ -376,6 +617,7 @@ func Test_alerter(t *testing.T) {
 				}
 			}
 			close(tt.args.chSignal)
+			<-done
 			if w.String() == "" {
 				t.Error("output is empty")
 			} else {
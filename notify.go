@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	notifyConsole = "console"
+	notifySound   = "sound"
+	notifyFile    = "file"
+	notifyDesktop = "desktop"
+
+	// alerts waiting for a slow notifier, the next ones are dropped for it
+	notifyQueueSize = 32
+	// no sound right after the start, when the alerts of the first tick are coming
+	soundQuietPeriod = time.Second * 20
+)
+
+type (
+	// notifier delivers the alert somewhere to get our attention
+	notifier interface {
+		name() string
+		notify(ctx context.Context, sig registrySignal) error
+	}
+	consoleNotifier struct {
+		w        io.Writer
+		registry map[int64]registryItem
+	}
+	soundNotifier struct {
+		started time.Time
+	}
+	fileNotifier struct {
+		mux      sync.Mutex
+		file     *os.File
+		registry map[int64]registryItem
+	}
+	desktopNotifier struct {
+		command string
+	}
+)
+
+// parses a comma separated list of notifiers
+func parseNotifiers(s string) (map[string]bool, error) {
+	var enabled = make(map[string]bool)
+	for _, n := range strings.Split(s, ",") {
+		switch n = strings.TrimSpace(n); n {
+		case "":
+		case notifyConsole, notifySound, notifyFile, notifyDesktop, notifyWebhook:
+			enabled[n] = true
+		default:
+			return nil, fmt.Errorf("unknown notifier %q", n)
+		}
+	}
+	return enabled, nil
+}
+
+// writes the alert as text
+func formatAlert(w io.Writer, registry map[int64]registryItem, sig registrySignal) {
+	fmt.Fprintln(w, "*********************************")
+	for _, warning := range sig.warnings {
+		fmt.Fprintf(w, "!!! POSSIBLE SCAM: %s\n", warning)
+	}
+	if sig.suspicious {
+		fmt.Fprintln(w, "NOT A DEAL, items are asked in return")
+	}
+	if sig.wanted {
+		fmt.Fprintln(w, "WANTED")
+	}
+	if sig.bid > 0 {
+		fmt.Fprintln(w, "AUCTION ENDING")
+	}
+	if sig.contract.Type == courier {
+		fmt.Fprintln(w, "COURIER")
+	}
+	fmt.Fprintln(w, sig.contract.Title)
+	fmt.Fprintf(w, "Region: %s\n", getRegionName(sig.region))
+	fmt.Fprintf(w, "Price: %0.3f M\n", sig.contract.Price/1000000)
+	if sig.contract.Type == auction {
+		if sig.contract.Buyout > 0 {
+			fmt.Fprintf(w, "Buyout: %0.3f M\n", sig.contract.Buyout/1000000)
+		}
+		if sig.bid > 0 {
+			fmt.Fprintf(w, "Current bid: %0.3f M\nExpires: %s\n", sig.bid/1000000, sig.contract.DateExpired.Format(time.RFC3339))
+		}
+	}
+	if sig.contract.Reward > 0 {
+		fmt.Fprintf(w, "Reward: %0.3f M\n", sig.contract.Reward/1000000)
+	}
+	if sig.contract.Type == courier {
+		perJump, perM3 := getCourierRates(sig.contract, sig.jumps)
+		fmt.Fprintf(w, "Collateral: %0.3f M\nVolume: %0.1f m3\nJumps: %d\n", sig.contract.Collateral/1000000, sig.contract.Volume, sig.jumps)
+		fmt.Fprintf(w, "Per jump: %0.3f M\nPer m3: %0.0f ISK\nDays to complete: %d\n", perJump/1000000, perM3, sig.contract.DaysToComplete)
+	}
+	for _, s := range sig.items {
+		fmt.Fprintln(w, "---------------------------------")
+		if t, ok := registry[s.TypeId]; ok {
+			fmt.Fprintf(w, "Item: %s\n", t.TypeName)
+		}
+		if s.Runs > 0 {
+			fmt.Fprintf(w, "Quantity: %d\nRuns: %d\n", s.Quantity, s.Runs)
+		} else {
+			fmt.Fprintf(w, "Quantity: %d\nORIGINAL\n", s.Quantity)
+		}
+	}
+	fmt.Fprintln(w, "*********************************")
+	fmt.Fprintln(w, "")
+}
+
+// returns the short title and the text of the alert
+func getAlertSummary(sig registrySignal) (string, string) {
+	var (
+		kind  = "Found"
+		title = sig.contract.Title
+		price = fmt.Sprintf("price %0.3f M", sig.contract.Price/1000000)
+	)
+	switch {
+	case sig.suspicious:
+		kind = "Suspicious"
+	case sig.wanted:
+		kind, price = "Wanted", fmt.Sprintf("reward %0.3f M", sig.contract.Reward/1000000)
+	case sig.bid > 0:
+		kind, price = "Auction ending", fmt.Sprintf("bid %0.3f M", sig.bid/1000000)
+	case sig.contract.Type == auction:
+		price = fmt.Sprintf("buyout %0.3f M", sig.contract.Buyout/1000000)
+	case sig.contract.Type == courier:
+		kind, price = "Courier", fmt.Sprintf("reward %0.3f M, %d jumps", sig.contract.Reward/1000000, sig.jumps)
+	}
+	if title == "" {
+		title = fmt.Sprintf("contract %d", sig.contract.Id)
+	}
+	if len(sig.warnings) > 0 {
+		kind = "Possible scam! " + kind
+	}
+	return fmt.Sprintf("%s: %s", kind, title), fmt.Sprintf("%s, %s", getRegionName(sig.region), price)
+}
+
+func (c consoleNotifier) name() string {
+	return notifyConsole
+}
+
+func (c consoleNotifier) notify(_ context.Context, sig registrySignal) error {
+	formatAlert(c.w, c.registry, sig)
+	return nil
+}
+
+func (s soundNotifier) name() string {
+	return notifySound
+}
+
+// there is no sound after the context is cancelled
+func (s soundNotifier) notify(ctx context.Context, _ registrySignal) error {
+	if ctx.Err() != nil || time.Since(s.started) < soundQuietPeriod {
+		return nil
+	}
+	return warning()
+}
+
+func openFileNotifier(path string, registry map[int64]registryItem) (*fileNotifier, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileNotifier{file: file, registry: registry}, nil
+}
+
+func (f *fileNotifier) name() string {
+	return notifyFile
+}
+
+func (f *fileNotifier) notify(_ context.Context, sig registrySignal) error {
+	var b strings.Builder
+	fmt.Fprintln(&b, time.Now().Format(time.RFC3339))
+	formatAlert(&b, f.registry, sig)
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	_, err := f.file.WriteString(b.String())
+	return err
+}
+
+func (f *fileNotifier) Close() error {
+	return f.file.Close()
+}
+
+// the command showing desktop notifications is looked up once, there is no point in failing on every alert
+func newDesktopNotifier() (desktopNotifier, error) {
+	var name string
+	switch runtime.GOOS {
+	case "linux":
+		name = "notify-send"
+	case "darwin":
+		name = "osascript"
+	default:
+		return desktopNotifier{}, fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+	command, err := exec.LookPath(name)
+	if err != nil {
+		return desktopNotifier{}, err
+	}
+	return desktopNotifier{command: command}, nil
+}
+
+func (d desktopNotifier) name() string {
+	return notifyDesktop
+}
+
+func (d desktopNotifier) notify(ctx context.Context, sig registrySignal) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	title, text := getAlertSummary(sig)
+	if runtime.GOOS == "darwin" {
+		return exec.CommandContext(ctx, d.command, "-e", fmt.Sprintf("display notification %q with title %q", text, title)).Run()
+	}
+	return exec.CommandContext(ctx, d.command, "--app-name=jitaScan", title, text).Run()
+}
+
+// delivers every signal to all notifiers until the channel is closed, so the pending alerts are flushed on shutdown,
+// every notifier has its own queue, a slow or failing one does not hold up the rest
+func alerter(ctx context.Context, notifiers []notifier, chSignal <-chan registrySignal) {
+	var (
+		wg     sync.WaitGroup
+		queues = make([]chan registrySignal, len(notifiers))
+	)
+	for i, n := range notifiers {
+		queues[i] = make(chan registrySignal, notifyQueueSize)
+		wg.Add(1)
+		go func(n notifier, queue <-chan registrySignal) {
+			defer wg.Done()
+			for sig := range queue {
+				if err := n.notify(ctx, sig); err != nil {
+					ifErrorPrint(fmt.Errorf("%s notifier: %s", n.name(), err))
+				}
+			}
+		}(n, queues[i])
+	}
+	for sig := range chSignal {
+		for i, queue := range queues {
+			select {
+			case queue <- sig:
+			default:
+				ifErrorPrint(fmt.Errorf("%s notifier is too slow, alert on contract %d is dropped", notifiers[i].name(), sig.contract.Id))
+			}
+		}
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+}
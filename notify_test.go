@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type (
+	failingNotifier struct{}
+	// blockingNotifier hangs until it is released
+	blockingNotifier struct {
+		release chan struct{}
+	}
+	countingNotifier struct {
+		mux   sync.Mutex
+		count int
+	}
+)
+
+func (f failingNotifier) name() string {
+	return "failing"
+}
+
+func (f failingNotifier) notify(context.Context, registrySignal) error {
+	return errors.New("always fails")
+}
+
+func (b blockingNotifier) name() string {
+	return "blocking"
+}
+
+func (b blockingNotifier) notify(context.Context, registrySignal) error {
+	<-b.release
+	return nil
+}
+
+func (c *countingNotifier) name() string {
+	return "counting"
+}
+
+func (c *countingNotifier) notify(context.Context, registrySignal) error {
+	c.mux.Lock()
+	c.count++
+	c.mux.Unlock()
+	return nil
+}
+
+func (c *countingNotifier) get() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.count
+}
+
+func Test_alerter_independentNotifiers(t *testing.T) {
+	var (
+		counter  = &countingNotifier{}
+		blocking = blockingNotifier{release: make(chan struct{})}
+		chSignal = make(chan registrySignal)
+		done     = make(chan struct{})
+		signals  = notifyQueueSize * 2
+	)
+	go func() {
+		defer close(done)
+		alerter(context.Background(), []notifier{failingNotifier{}, blocking, counter}, chSignal)
+	}()
+	for i := 0; i < signals; i++ {
+		chSignal <- registrySignal{contract: contract{Id: int64(i), Type: itemExchange}}
+		// the blocking notifier overflows its queue, but the rest keep getting alerts
+		deadline := time.Now().Add(time.Second)
+		for counter.get() != i+1 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	close(chSignal)
+	close(blocking.release)
+	<-done
+	if count := counter.get(); count != signals {
+		t.Errorf("expected %d alerts, got %d", signals, count)
+	}
+}
+
+func Test_parseNotifiers(t *testing.T) {
+	enabled, err := parseNotifiers("console, desktop,,file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enabled) != 3 || !enabled[notifyConsole] || !enabled[notifyDesktop] || !enabled[notifyFile] {
+		t.Errorf("parseNotifiers() = %v", enabled)
+	}
+	if _, err = parseNotifiers("console,pager"); err == nil {
+		t.Error("expected an error for an unknown notifier")
+	}
+}
+
+func Test_formatAlert_scam(t *testing.T) {
+	var w bytes.Buffer
+	formatAlert(&w, nil, registrySignal{
+		contract: contract{Title: "Raven BPC", Type: itemExchange},
+		warnings: []string{"the title mentions 688, Raven Blueprint, but it is not in the contract"},
+	})
+	if !strings.Contains(w.String(), "POSSIBLE SCAM") {
+		t.Errorf("expected a warning badge in %q", w.String())
+	}
+}
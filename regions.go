@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the list of regions changes very rarely, so there is no point in asking ESI for it
+var regionNames = map[string]string{
+	"10000001": "Derelik",
+	"10000002": "The Forge",
+	"10000003": "Vale of the Silent",
+	"10000004": "UUA-F4",
+	"10000005": "Detorid",
+	"10000006": "Wicked Creek",
+	"10000007": "Cache",
+	"10000008": "Scalding Pass",
+	"10000009": "Insmother",
+	"10000010": "Tribute",
+	"10000011": "Great Wildlands",
+	"10000012": "Curse",
+	"10000013": "Malpais",
+	"10000014": "Catch",
+	"10000015": "Venal",
+	"10000016": "Lonetrek",
+	"10000017": "J7HZ-F",
+	"10000018": "The Spire",
+	"10000019": "A821-A",
+	"10000020": "Tash-Murkon",
+	"10000021": "Outer Passage",
+	"10000022": "Stain",
+	"10000023": "Pure Blind",
+	"10000025": "Immensea",
+	"10000027": "Etherium Reach",
+	"10000028": "Molden Heath",
+	"10000029": "Geminate",
+	"10000030": "Heimatar",
+	"10000031": "Impass",
+	"10000032": "Sinq Laison",
+	"10000033": "The Citadel",
+	"10000034": "The Kalevala Expanse",
+	"10000035": "Deklein",
+	"10000036": "Devoid",
+	"10000037": "Everyshore",
+	"10000038": "The Bleak Lands",
+	"10000039": "Esoteria",
+	"10000040": "Oasa",
+	"10000041": "Syndicate",
+	"10000042": "Metropolis",
+	"10000043": "Domain",
+	"10000044": "Solitude",
+	"10000045": "Tenal",
+	"10000046": "Fade",
+	"10000047": "Providence",
+	"10000048": "Placid",
+	"10000049": "Khanid",
+	"10000050": "Querious",
+	"10000051": "Cloud Ring",
+	"10000052": "Kador",
+	"10000053": "Cobalt Edge",
+	"10000054": "Aridia",
+	"10000055": "Branch",
+	"10000056": "Feythabolis",
+	"10000057": "Outer Ring",
+	"10000058": "Fountain",
+	"10000059": "Paragon Soul",
+	"10000060": "Delve",
+	"10000061": "Tenerifis",
+	"10000062": "Omist",
+	"10000063": "Period Basis",
+	"10000064": "Essence",
+	"10000065": "Kor-Azor",
+	"10000066": "Perrigen Falls",
+	"10000067": "Genesis",
+	"10000068": "Verge Vendor",
+	"10000069": "Black Rise",
+	"10000070": "Pochven",
+}
+
+// accepts both the region ID and its name, the name is case insensitive
+func resolveRegion(s string) (string, error) {
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return s, nil
+	}
+	for id, name := range regionNames {
+		if strings.EqualFold(name, s) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("unknown region: %s", s)
+}
+
+// returns the region name for displaying, unknown regions are displayed by ID
+func getRegionName(id string) string {
+	if name, ok := regionNames[id]; ok {
+		return name
+	}
+	return id
+}
+
+// parses a comma separated list of region IDs or names, duplicates are skipped
+func parseRegions(s string) ([]string, error) {
+	var (
+		regions []string
+		known   = make(map[string]struct{})
+	)
+	for _, region := range strings.Split(s, ",") {
+		if region = strings.TrimSpace(region); region == "" {
+			continue
+		}
+		id, err := resolveRegion(region)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := known[id]; ok {
+			continue
+		}
+		known[id] = struct{}{}
+		regions = append(regions, id)
+	}
+	if len(regions) == 0 {
+		return nil, errors.New("no region specified")
+	}
+	return regions, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "single id",
+			s:    regionIdJita,
+			want: []string{regionIdJita},
+		},
+		{
+			name: "names and ids",
+			s:    "The Forge, domain,10000032,Heimatar",
+			want: []string{regionIdJita, "10000043", "10000032", "10000030"},
+		},
+		{
+			name: "duplicates",
+			s:    "10000002,The Forge",
+			want: []string{regionIdJita},
+		},
+		{
+			name:    "unknown name",
+			s:       "The Forge,Jita",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			s:       " , ",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRegions(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseRegions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRegions() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,9 +5,24 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
-const registryPath = "./registry.json"
+const (
+	registryPath = "./registry.json"
+
+	optionOriginal = "bpo"
+	optionIgnore   = "ignore"
+	optionMinME    = "me"
+	optionMinTE    = "te"
+	optionMEBonus  = "mebonus"
+	optionTEBonus  = "tebonus"
+	optionMinRuns  = "minruns"
+	optionMaxRuns  = "maxruns"
+	optionMinQty   = "minqty"
+	optionMaxQty   = "maxqty"
+)
 
 func createRegistry() {
 	ifErrorFatal(saveRegistry(nil))
@@ -34,6 +49,20 @@ func loadRegistry() (items map[int64]registryItem) {
 	return
 }
 
+// reads the registry without creating it, a missing registry is empty
+func readRegistry() (items map[int64]registryItem, err error) {
+	f, err := os.Open(registryPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer deferWithPrintError(f.Close)
+	err = json.NewDecoder(f).Decode(&items)
+	return
+}
+
 func saveRegistry(items map[int64]registryItem) error {
 	f, err := os.Create(registryPath)
 	ifErrorFatal(err)
@@ -42,26 +71,191 @@ func saveRegistry(items map[int64]registryItem) error {
 	return encoder.Encode(items)
 }
 
+// parses the typeID, the price of a single run of a copy and optional settings in the key=value form
+func parseRegistryItem(s string) (item registryItem, err error) {
+	f := strings.Fields(s)
+	if len(f) < 2 {
+		return item, errors.New("typeID and price expected")
+	}
+	if item.TypeId, err = strconv.ParseInt(f[0], 10, 64); err != nil {
+		return item, err
+	}
+	if item.Price, err = strconv.ParseFloat(f[1], 64); err != nil {
+		return item, err
+	}
+	for _, option := range f[2:] {
+		if err = setRegistryOption(&item, option); err != nil {
+			return item, err
+		}
+	}
+	// such an item would never match
+	if item.MaxRuns > 0 && item.MinRuns > item.MaxRuns {
+		return item, fmt.Errorf("%s=%d is greater than %s=%d", optionMinRuns, item.MinRuns, optionMaxRuns, item.MaxRuns)
+	}
+	if item.MaxQuantity > 0 && item.MinQuantity > item.MaxQuantity {
+		return item, fmt.Errorf("%s=%d is greater than %s=%d", optionMinQty, item.MinQuantity, optionMaxQty, item.MaxQuantity)
+	}
+	return item, nil
+}
+
+func setRegistryOption(item *registryItem, option string) (err error) {
+	kv := strings.SplitN(option, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("wrong option format: %s", option)
+	}
+	switch key, value := kv[0], kv[1]; key {
+	case optionOriginal:
+		item.OriginalPrice, err = strconv.ParseFloat(value, 64)
+	case optionIgnore:
+		switch value {
+		case "bpo":
+			item.IgnoreOriginals = true
+		case "bpc":
+			item.IgnoreCopies = true
+		default:
+			err = fmt.Errorf("only bpo or bpc can be ignored, got %s", value)
+		}
+	case optionMinME:
+		item.MinME, err = parseResearchLevel(value, 10)
+	case optionMinTE:
+		item.MinTE, err = parseResearchLevel(value, 20)
+	case optionMEBonus:
+		item.MEBonus, err = strconv.ParseFloat(value, 64)
+	case optionTEBonus:
+		item.TEBonus, err = strconv.ParseFloat(value, 64)
+	case optionMinRuns:
+		item.MinRuns, err = parseLimit(value)
+	case optionMaxRuns:
+		item.MaxRuns, err = parseLimit(value)
+	case optionMinQty:
+		item.MinQuantity, err = parseLimit(value)
+	case optionMaxQty:
+		item.MaxQuantity, err = parseLimit(value)
+	default:
+		err = fmt.Errorf("unknown option: %s", key)
+	}
+	return err
+}
+
+// zero means that there is no limit
+func parseLimit(s string) (int32, error) {
+	limit, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	if limit < 0 {
+		return 0, fmt.Errorf("negative limit %d", limit)
+	}
+	return int32(limit), nil
+}
+
+func parseResearchLevel(s string, max int32) (int32, error) {
+	level, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	if level < 0 || int32(level) > max {
+		return 0, fmt.Errorf("research level %d is out of range 0..%d", level, max)
+	}
+	return int32(level), nil
+}
+
+func describeRegistryItem(item registryItem) string {
+	var parts []string
+	if item.IgnoreCopies {
+		parts = append(parts, "copies ignored")
+	} else {
+		parts = append(parts, fmt.Sprintf("%0.3f per run", item.Price))
+	}
+	if item.IgnoreOriginals {
+		parts = append(parts, "originals ignored")
+	} else {
+		parts = append(parts, fmt.Sprintf("%0.3f per original", item.OriginalPrice))
+	}
+	if item.MinME > 0 || item.MinTE > 0 {
+		parts = append(parts, fmt.Sprintf("ME >= %d, TE >= %d", item.MinME, item.MinTE))
+	}
+	if item.MinRuns > 0 || item.MaxRuns > 0 {
+		parts = append(parts, "runs "+describeLimits(item.MinRuns, item.MaxRuns))
+	}
+	if item.MinQuantity > 0 || item.MaxQuantity > 0 {
+		parts = append(parts, "quantity "+describeLimits(item.MinQuantity, item.MaxQuantity))
+	}
+	if item.SellPrice > 0 || item.SellOriginalPrice > 0 {
+		parts = append(parts, fmt.Sprintf("sell: %0.3f per run, %0.3f per original", item.SellPrice, item.SellOriginalPrice))
+	}
+	if item.MEBonus != 0 || item.TEBonus != 0 {
+		parts = append(parts, fmt.Sprintf("%+g%% per ME level, %+g%% per TE level", item.MEBonus, item.TEBonus))
+	}
+	return strings.Join(parts, ", ")
+}
+
 func addToRegistry(registry map[int64]registryItem, allTypes []itemType, newItem string) (map[int64]registryItem, string, error) {
-	var (
-		id    int64
-		price float64
-	)
-	_, err := fmt.Sscanf(newItem, "%d %f", &id, &price)
+	item, err := parseRegistryItem(newItem)
 	if err != nil {
 		return registry, "", err
 	}
-	typeName := getItemName(allTypes, id)
-	if typeName == "" {
+	item.TypeName = getItemName(allTypes, item.TypeId)
+	if item.TypeName == "" {
 		return registry, "", errors.New("cannot resolve item by ID")
 	}
 	if registry == nil {
 		registry = make(map[int64]registryItem)
 	}
-	registry[id] = registryItem{
-		TypeId:   id,
-		Price:    price,
-		TypeName: typeName,
+	if prev, ok := registry[item.TypeId]; ok {
+		item.SellPrice = prev.SellPrice
+		item.SellOriginalPrice = prev.SellOriginalPrice
+	}
+	registry[item.TypeId] = item
+	return registry, fmt.Sprintf("added %s\n", item.TypeName), saveRegistry(registry)
+}
+
+// parses the typeID, the price of a single run of a copy we sell and optional price of the original,
+// the rest of the options are settings of the buy side
+func parseSellItem(s string) (registryItem, error) {
+	f := strings.Fields(s)
+	if len(f) < 2 {
+		return registryItem{}, errors.New("typeID and price expected")
+	}
+	for _, option := range f[2:] {
+		if key := strings.SplitN(option, "=", 2)[0]; key != optionOriginal {
+			return registryItem{}, fmt.Errorf("only the %s option can be set for selling, got %s", optionOriginal, option)
+		}
+	}
+	return parseRegistryItem(s)
+}
+
+// sets the prices we accept to sell the type, the buy side settings of the type are kept
+func addSellToRegistry(registry map[int64]registryItem, allTypes []itemType, newItem string) (map[int64]registryItem, string, error) {
+	sell, err := parseSellItem(newItem)
+	if err != nil {
+		return registry, "", err
+	}
+	if registry == nil {
+		registry = make(map[int64]registryItem)
+	}
+	item, ok := registry[sell.TypeId]
+	if !ok {
+		item = registryItem{
+			TypeId:   sell.TypeId,
+			TypeName: getItemName(allTypes, sell.TypeId),
+		}
+		if item.TypeName == "" {
+			return registry, "", errors.New("cannot resolve item by ID")
+		}
+	}
+	item.SellPrice = sell.Price
+	item.SellOriginalPrice = sell.OriginalPrice
+	registry[item.TypeId] = item
+	return registry, fmt.Sprintf("selling %s\n", item.TypeName), saveRegistry(registry)
+}
+
+func describeLimits(min, max int32) string {
+	switch {
+	case max == 0:
+		return fmt.Sprintf(">= %d", min)
+	case min == 0:
+		return fmt.Sprintf("<= %d", max)
 	}
-	return registry, fmt.Sprintf("added %s\n", typeName), saveRegistry(registry)
+	return fmt.Sprintf("%d..%d", min, max)
 }
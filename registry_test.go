@@ -0,0 +1,130 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseRegistryItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		wantItem registryItem
+		wantErr  bool
+	}{
+		{
+			name:     "type and price",
+			s:        "17931 45",
+			wantItem: registryItem{TypeId: 17931, Price: 45},
+		},
+		{
+			name:     "original price",
+			s:        "17931 45.5 bpo=1200",
+			wantItem: registryItem{TypeId: 17931, Price: 45.5, OriginalPrice: 1200},
+		},
+		{
+			name:     "ignore copies",
+			s:        "17931 0 bpo=1200 ignore=bpc",
+			wantItem: registryItem{TypeId: 17931, OriginalPrice: 1200, IgnoreCopies: true},
+		},
+		{
+			name:     "research",
+			s:        "17931 45 me=8 te=16 mebonus=5 tebonus=1.5",
+			wantItem: registryItem{TypeId: 17931, Price: 45, MinME: 8, MinTE: 16, MEBonus: 5, TEBonus: 1.5},
+		},
+		{
+			name:     "limits",
+			s:        "17931 45 minruns=10 maxruns=100 minqty=1 maxqty=2",
+			wantItem: registryItem{TypeId: 17931, Price: 45, MinRuns: 10, MaxRuns: 100, MinQuantity: 1, MaxQuantity: 2},
+		},
+		{
+			name:    "negative limit",
+			s:       "17931 45 maxqty=-2",
+			wantErr: true,
+		},
+		{
+			name:    "min runs over max",
+			s:       "17931 45 minruns=50 maxruns=10",
+			wantErr: true,
+		},
+		{
+			name:    "min quantity over max",
+			s:       "17931 45 minqty=3 maxqty=2",
+			wantErr: true,
+		},
+		{
+			name:     "min runs without max",
+			s:        "17931 45 minruns=50",
+			wantItem: registryItem{TypeId: 17931, Price: 45, MinRuns: 50},
+		},
+		{
+			name:    "wrong ME",
+			s:       "17931 45 me=12",
+			wantErr: true,
+		},
+		{
+			name:    "no price",
+			s:       "17931",
+			wantErr: true,
+		},
+		{
+			name:    "unknown option",
+			s:       "17931 45 foo=bar",
+			wantErr: true,
+		},
+		{
+			name:    "wrong ignore",
+			s:       "17931 45 ignore=all",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotItem, err := parseRegistryItem(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseRegistryItem() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(gotItem, tt.wantItem) {
+				t.Errorf("parseRegistryItem() gotItem = %+v, want %+v", gotItem, tt.wantItem)
+			}
+		})
+	}
+}
+
+func Test_parseSellItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		wantItem registryItem
+		wantErr  bool
+	}{
+		{
+			name:     "copy and original prices",
+			s:        "17931 60 bpo=1500",
+			wantItem: registryItem{TypeId: 17931, Price: 60, OriginalPrice: 1500},
+		},
+		{
+			name:    "buy side options",
+			s:       "17931 60 minruns=10 me=5 ignore=bpo",
+			wantErr: true,
+		},
+		{
+			name:    "no price",
+			s:       "17931",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotItem, err := parseSellItem(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseSellItem() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(gotItem, tt.wantItem) {
+				t.Errorf("parseSellItem() gotItem = %+v, want %+v", gotItem, tt.wantItem)
+			}
+		})
+	}
+}
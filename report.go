@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+type soldItem struct {
+	contract regionContract
+	item     contractItem
+}
+
+// prints the watched items from contracts that disappeared before expiration, they were most likely accepted
+func reportOperations(state programState) {
+	// the registry only gives the names, the report does not create it
+	registry, err := readRegistry()
+	ifErrorPrint(err)
+	db, err := connectToDatabase()
+	ifErrorFatal(err)
+	_, err = readSeenContractsLog(state.report.storePath, db)
+	ifErrorFatal(err)
+	since := time.Now().Add(-time.Hour * 24 * time.Duration(state.report.days))
+	printSoldReport(state.output, registry, getSoldContractsDB(db, since))
+}
+
+func printSoldReport(w io.Writer, registry map[int64]registryItem, contracts []regionContract) {
+	var (
+		types  []int64
+		byType = make(map[int64][]soldItem)
+	)
+	for _, c := range contracts {
+		// an auction that disappeared early was bought out, without the buyout it could only be withdrawn
+		if _, ok := getAskingPrice(c.contract); !ok {
+			continue
+		}
+		for _, item := range c.Watched {
+			if _, ok := byType[item.TypeId]; !ok {
+				types = append(types, item.TypeId)
+			}
+			byType[item.TypeId] = append(byType[item.TypeId], soldItem{contract: c, item: item})
+		}
+	}
+	if len(types) == 0 {
+		fmt.Fprintln(w, "no sold items found")
+		return
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	for _, typeId := range types {
+		var (
+			sold = byType[typeId]
+			name = fmt.Sprintf("unknown ID %d", typeId)
+		)
+		if t, ok := registry[typeId]; ok {
+			name = t.TypeName
+		}
+		sort.Slice(sold, func(i, j int) bool {
+			return sold[i].contract.Disappeared.Before(sold[j].contract.Disappeared)
+		})
+		fmt.Fprintf(w, "%s: %d sold\n", name, len(sold))
+		for _, s := range sold {
+			var (
+				issued   = s.contract.DateIssued
+				price, _ = getAskingPrice(s.contract.contract)
+			)
+			if issued.IsZero() {
+				issued = s.contract.FirstSeen
+			}
+			fmt.Fprintf(w, "  %s  %s  %s  price: %0.3f M  on market: %s\n",
+				s.contract.Disappeared.Format("2006-01-02 15:04"),
+				getRegionName(s.contract.Region),
+				describeItem(s.item),
+				price/1000000,
+				s.contract.Disappeared.Sub(issued).Round(time.Minute),
+			)
+		}
+	}
+}
+
+func describeItem(item contractItem) string {
+	if item.Runs > 0 {
+		return fmt.Sprintf("quantity: %d, runs: %d", item.Quantity, item.Runs)
+	}
+	return fmt.Sprintf("quantity: %d, ORIGINAL", item.Quantity)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_printSoldReport(t *testing.T) {
+	var (
+		w        = bytes.NewBuffer([]byte{})
+		now      = time.Now()
+		registry = map[int64]registryItem{
+			123: {TypeId: 123, Price: 40, TypeName: "123, Foo Blueprint"},
+		}
+		contracts = []regionContract{
+			{
+				Region:      regionIdJita,
+				Disappeared: now,
+				Watched:     []contractItem{{TypeId: 123, Runs: 10, Quantity: 1, IsIncluded: true}},
+				contract: contract{
+					Id:         1,
+					Price:      380000000,
+					DateIssued: now.Add(-time.Hour * 3),
+				},
+			},
+			{
+				Region:      regionIdJita,
+				Disappeared: now,
+				Watched:     []contractItem{{TypeId: 123, Runs: 20, Quantity: 1, IsIncluded: true}},
+				contract: contract{
+					Id:         2,
+					Type:       auction,
+					Price:      100000000,
+					Buyout:     700000000,
+					DateIssued: now.Add(-time.Hour),
+				},
+			},
+			{
+				Region:      regionIdJita,
+				Disappeared: now,
+				Watched:     []contractItem{{TypeId: 123, Runs: 30, Quantity: 1, IsIncluded: true}},
+				contract: contract{
+					Id:         3,
+					Type:       auction,
+					Price:      100000000,
+					DateIssued: now.Add(-time.Hour),
+				},
+			},
+		}
+	)
+	printSoldReport(w, registry, contracts)
+	for _, want := range []string{"123, Foo Blueprint: 2 sold", "The Forge", "runs: 10", "price: 380.000 M", "on market: 3h0m0s", "price: 700.000 M"} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("report does not contain %q:\n%s", want, w.String())
+		}
+	}
+	// the starting bid is not the price and the auction without buyout was not sold
+	for _, unwanted := range []string{"price: 100.000 M", "runs: 30"} {
+		if strings.Contains(w.String(), unwanted) {
+			t.Errorf("report contains %q:\n%s", unwanted, w.String())
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const (
+	// ESI responds with this status when the error limit is exceeded
+	statusErrorLimited = 420
+
+	retryBaseDelay = time.Millisecond * 500
+)
+
+type (
+	retryPolicy struct {
+		attempts int
+		maxDelay time.Duration
+	}
+	retryClient struct {
+		client httpClient
+		policy retryPolicy
+	}
+)
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, statusErrorLimited:
+		return true
+	}
+	return false
+}
+
+// exponential backoff with full jitter, so parallel workers do not retry at the same moment
+func (p retryPolicy) delay(attempt int) time.Duration {
+	var d = p.maxDelay
+	if attempt < 16 {
+		if exp := retryBaseDelay << uint(attempt); exp < d {
+			d = exp
+		}
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d))) + 1
+}
+
+func (c retryClient) Do(req *http.Request) (*http.Response, error) {
+	// only idempotent requests can be safely repeated
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return c.client.Do(req)
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.client.Do(req)
+		if attempt+1 >= c.policy.attempts || (err == nil && !isRetryableStatus(resp.StatusCode)) {
+			return resp, err
+		}
+		if err == nil {
+			deferWithPrintError(resp.Body.Close)
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(c.policy.delay(attempt)):
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type statusClientTest struct {
+	statuses []int
+	calls    int
+}
+
+func (s *statusClientTest) Do(r *http.Request) (*http.Response, error) {
+	defer func() { s.calls++ }()
+	if s.calls >= len(s.statuses) {
+		return nil, errors.New("unexpected request")
+	}
+	if s.statuses[s.calls] == 0 {
+		return nil, errors.New("connection reset")
+	}
+	return &http.Response{
+		StatusCode: s.statuses[s.calls],
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    r,
+	}, nil
+}
+
+func Test_retryClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statuses   []int
+		wantStatus int
+		wantCalls  int
+		wantErr    bool
+	}{
+		{
+			name:       "success",
+			method:     http.MethodGet,
+			statuses:   []int{200},
+			wantStatus: 200,
+			wantCalls:  1,
+		},
+		{
+			name:       "transient failures",
+			method:     http.MethodGet,
+			statuses:   []int{0, 502, statusErrorLimited, 200},
+			wantStatus: 200,
+			wantCalls:  4,
+		},
+		{
+			name:       "not retryable",
+			method:     http.MethodGet,
+			statuses:   []int{500, 200},
+			wantStatus: 500,
+			wantCalls:  1,
+		},
+		{
+			name:      "attempts exhausted",
+			method:    http.MethodGet,
+			statuses:  []int{504, 503, 502, 0, 200},
+			wantCalls: 4,
+			wantErr:   true,
+		},
+		{
+			name:       "not idempotent",
+			method:     http.MethodPost,
+			statuses:   []int{503, 200},
+			wantStatus: 503,
+			wantCalls:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				client = statusClientTest{statuses: tt.statuses}
+				retry  = retryClient{
+					client: &client,
+					policy: retryPolicy{attempts: 4, maxDelay: time.Millisecond},
+				}
+			)
+			req, err := http.NewRequest(tt.method, "https://localhost/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := retry.Do(req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && resp.StatusCode != tt.wantStatus {
+				t.Errorf("Do() status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if client.calls != tt.wantCalls {
+				t.Errorf("Do() calls = %d, want %d", client.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func Test_retryPolicy_delay(t *testing.T) {
+	var policy = retryPolicy{attempts: 10, maxDelay: time.Second * 3}
+	for attempt := 0; attempt < 20; attempt++ {
+		if d := policy.delay(attempt); d <= 0 || d > policy.maxDelay {
+			t.Errorf("delay(%d) = %v, expected in range (0, %v]", attempt, d, policy.maxDelay)
+		}
+	}
+}
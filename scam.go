@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// titles usually omit the suffix of the blueprint name
+const blueprintSuffix = " blueprint"
+
+// issuerFilter holds the issuers and their corporations we do or do not deal with
+type issuerFilter struct {
+	blockedIssuers      map[int64]struct{}
+	allowedIssuers      map[int64]struct{}
+	blockedCorporations map[int64]struct{}
+	allowedCorporations map[int64]struct{}
+}
+
+func makeIdSet(ids []int64) map[int64]struct{} {
+	var set = make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+func newIssuerFilter(config programConfig) issuerFilter {
+	return issuerFilter{
+		blockedIssuers:      makeIdSet(config.BlockedIssuers),
+		allowedIssuers:      makeIdSet(config.AllowedIssuers),
+		blockedCorporations: makeIdSet(config.BlockedCorporations),
+		allowedCorporations: makeIdSet(config.AllowedCorporations),
+	}
+}
+
+// checks the issuer of the contract, if there are allow lists the issuer or its corporation has to be in one of them
+func checkIssuer(filter issuerFilter, contract contract) (bool, string) {
+	if _, ok := filter.blockedIssuers[contract.IssuerId]; ok {
+		return false, fmt.Sprintf("issuer %d is blocked", contract.IssuerId)
+	}
+	if _, ok := filter.blockedCorporations[contract.IssuerCorporationId]; ok {
+		return false, fmt.Sprintf("corporation %d is blocked", contract.IssuerCorporationId)
+	}
+	if len(filter.allowedIssuers) == 0 && len(filter.allowedCorporations) == 0 {
+		return true, ""
+	}
+	if _, ok := filter.allowedIssuers[contract.IssuerId]; ok {
+		return true, ""
+	}
+	if _, ok := filter.allowedCorporations[contract.IssuerCorporationId]; ok {
+		return true, ""
+	}
+	return false, fmt.Sprintf("issuer %d is not allowed", contract.IssuerId)
+}
+
+// the contract would be suitable if it did not ask for items in return, a cheap offer like this is a common bait
+func isBaitContract(registry map[int64]registryItem, contract contract, items []contractItem) bool {
+	var included []contractItem
+	for _, item := range items {
+		if item.IsIncluded {
+			included = append(included, item)
+		}
+	}
+	return len(included) < len(items) && checkSuitable(registry, contract, included)
+}
+
+// returns the name of the registered type as it is usually written in titles
+func getTitleName(item registryItem) string {
+	name := item.TypeName
+	if i := strings.Index(name, ", "); i >= 0 {
+		name = name[i+2:]
+	}
+	return strings.TrimSuffix(strings.ToLower(name), blueprintSuffix)
+}
+
+// returns the signs of a scam: the title promises a watched item that is not included,
+// or the watched items are offered together with the items asked in return
+func getScamWarnings(registry map[int64]registryItem, contract contract, items []contractItem) (warnings []string) {
+	var (
+		contents = make(map[int64]struct{})
+		names    []string
+		offered  = false
+		excluded = false
+	)
+	for _, item := range items {
+		contents[item.TypeId] = struct{}{}
+		if watch, ok := registry[item.TypeId]; ok {
+			names = append(names, getTitleName(watch))
+			offered = offered || item.IsIncluded
+		}
+		excluded = excluded || !item.IsIncluded
+	}
+	if title := strings.ToLower(contract.Title); title != "" {
+		for typeId, watch := range registry {
+			name := getTitleName(watch)
+			if _, ok := contents[typeId]; ok || len(name) < 3 || !strings.Contains(title, name) {
+				continue
+			}
+			// the name may be a part of the name of the included item
+			var partOf = false
+			for _, n := range names {
+				partOf = partOf || strings.Contains(n, name)
+			}
+			if !partOf {
+				warnings = append(warnings, fmt.Sprintf("the title mentions %s, but it is not in the contract", watch.TypeName))
+			}
+		}
+	}
+	if excluded && offered {
+		warnings = append(warnings, "watched items are offered together with the items asked in return")
+	}
+	return warnings
+}
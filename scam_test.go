@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"strconv"
+	"testing"
+)
+
+func Test_checkIssuer(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   programConfig
+		contract contract
+		want     bool
+	}{
+		{
+			name:     "no lists",
+			contract: contract{IssuerId: 90000001, IssuerCorporationId: 98000001},
+			want:     true,
+		},
+		{
+			name:     "blocked issuer",
+			config:   programConfig{BlockedIssuers: []int64{90000001}},
+			contract: contract{IssuerId: 90000001, IssuerCorporationId: 98000001},
+			want:     false,
+		},
+		{
+			name:     "blocked corporation",
+			config:   programConfig{BlockedCorporations: []int64{98000001}, AllowedIssuers: []int64{90000001}},
+			contract: contract{IssuerId: 90000001, IssuerCorporationId: 98000001},
+			want:     false,
+		},
+		{
+			name:     "allowed corporation",
+			config:   programConfig{AllowedIssuers: []int64{90000002}, AllowedCorporations: []int64{98000001}},
+			contract: contract{IssuerId: 90000001, IssuerCorporationId: 98000001},
+			want:     true,
+		},
+		{
+			name:     "not allowed",
+			config:   programConfig{AllowedIssuers: []int64{90000002}},
+			contract: contract{IssuerId: 90000001, IssuerCorporationId: 98000001},
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, reason := checkIssuer(newIssuerFilter(tt.config), tt.contract); got != tt.want {
+				t.Errorf("checkIssuer() = %v (%s), want %v", got, reason, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getScamWarnings(t *testing.T) {
+	registry := map[int64]registryItem{
+		688:   {TypeId: 688, TypeName: "688, Raven Blueprint"},
+		17637: {TypeId: 17637, TypeName: "17637, Raven Navy Issue Blueprint"},
+		1003:  {TypeId: 1003, TypeName: "1003, Drake Blueprint"},
+	}
+	tests := []struct {
+		name  string
+		title string
+		items []contractItem
+		want  int
+	}{
+		{
+			name:  "honest",
+			title: "Raven BPC x10",
+			items: []contractItem{{TypeId: 688, IsIncluded: true, Runs: 10, Quantity: 1}},
+			want:  0,
+		},
+		{
+			name:  "bait title",
+			title: "Raven BPC x10",
+			items: []contractItem{{TypeId: 1003, IsIncluded: true, Runs: 10, Quantity: 1}},
+			want:  1,
+		},
+		{
+			name:  "name is a part of the included item",
+			title: "Raven Navy Issue BPC",
+			items: []contractItem{{TypeId: 17637, IsIncluded: true, Runs: 1, Quantity: 1}},
+			want:  0,
+		},
+		{
+			name:  "items asked in return",
+			title: "",
+			items: []contractItem{
+				{TypeId: 688, IsIncluded: true, Runs: 10, Quantity: 1},
+				{TypeId: 34, IsIncluded: false, Quantity: 1000000},
+			},
+			want: 1,
+		},
+		{
+			name:  "want to buy",
+			title: "WTB Raven BPC",
+			items: []contractItem{{TypeId: 688, IsIncluded: false, Runs: 10, Quantity: 1}},
+			want:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getScamWarnings(registry, contract{Title: tt.title}, tt.items)
+			if len(got) != tt.want {
+				t.Errorf("getScamWarnings() = %v, want %d warnings", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_monCheckContract_bait(t *testing.T) {
+	registry := map[int64]registryItem{688: {TypeId: 688, Price: 10, TypeName: "688, Raven Blueprint"}}
+	tests := []struct {
+		name       string
+		price      float64
+		items      string
+		want       bool
+		suspicious bool
+	}{
+		{
+			name:  "suitable",
+			price: 90000000,
+			items: `[{"record_id":1,"type_id":688,"is_included":true,"runs":10,"quantity":1}]`,
+			want:  true,
+		},
+		{
+			name:  "items asked in return",
+			price: 90000000,
+			items: `[{"record_id":1,"type_id":688,"is_included":true,"runs":10,"quantity":1},` +
+				`{"record_id":2,"type_id":34,"is_included":false,"quantity":1000000}]`,
+			want:       true,
+			suspicious: true,
+		},
+		{
+			name:  "too expensive anyway",
+			price: 900000000,
+			items: `[{"record_id":1,"type_id":688,"is_included":true,"runs":10,"quantity":1},` +
+				`{"record_id":2,"type_id":34,"is_included":false,"quantity":1000000}]`,
+			want: false,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				id       = int64(1000 + i)
+				chSignal = make(chan registrySignal, 2)
+				eve      = eveConnector{client: &pathClientTest{data: map[string]string{
+					"/latest/contracts/public/items/" + strconv.FormatInt(id, 10): tt.items,
+				}}}
+			)
+			_, err := monCheckContract(context.Background(), regionIdJita, contract{Id: id, Type: itemExchange, Price: tt.price}, eve, registry, chSignal, ioutil.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(chSignal) > 0) != tt.want {
+				t.Fatalf("expected alert %v, got %d alerts", tt.want, len(chSignal))
+			}
+			if tt.want {
+				sig := <-chSignal
+				if sig.suspicious != tt.suspicious || (tt.suspicious && len(sig.warnings) == 0) {
+					t.Errorf("unexpected signal %+v", sig)
+				}
+			}
+		})
+	}
+}
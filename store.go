@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-memdb"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	seenContractsPath = "./contracts.log"
+
+	// the log is not compacted until it has at least this number of outdated records
+	compactThreshold = 1000
+)
+
+// seenContractsLog is an append-only log of the checked contracts, it restores the database after restart,
+// so the contracts created while the tool was down are not lost
+type seenContractsLog struct {
+	mux     sync.Mutex
+	path    string
+	file    *os.File
+	records int
+}
+
+func openSeenContractsLog(path string) (*seenContractsLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &seenContractsLog{path: path, file: f}, nil
+}
+
+// loads all records of the log into the contracts table and returns their number
+func (l *seenContractsLog) replay(db *memdb.MemDB) (int, error) {
+	if l == nil {
+		return 0, nil
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	count, err := loadSeenContracts(l.file, l.file.Name(), db)
+	if err != nil {
+		return count, err
+	}
+	l.records = count
+	return count, terminateLine(l.file)
+}
+
+// reads the log of the monitoring without changing it, the monitoring may be writing to it right now
+func readSeenContractsLog(path string, db *memdb.MemDB) (int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer deferWithPrintError(f.Close)
+	return loadSeenContracts(f, path, db)
+}
+
+// loads all records into the contracts table and returns their number
+func loadSeenContracts(r io.Reader, name string, db *memdb.MemDB) (int, error) {
+	var (
+		count = 0
+		txn   = db.Txn(true)
+	)
+	defer txn.Abort()
+	err := readLines(r, func(line []byte) error {
+		var c regionContract
+		if err := json.Unmarshal(line, &c); err != nil {
+			// the last record could be truncated if the process was killed while writing
+			ifErrorPrint(fmt.Errorf("skipped broken record in %s: %s", name, err))
+			return nil
+		}
+		if err := txn.Insert(tableContracts, c); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	txn.Commit()
+	return count, nil
+}
+
+// calls the function for every non-empty line, unlike bufio.Scanner it has no limit on the line length
+func readLines(r io.Reader, fn func(line []byte) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if fnErr := fn(line); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// makes sure that the next record appended to the file does not stick to the truncated one
+func terminateLine(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	var last = make([]byte, 1)
+	if _, err = f.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] != '\n' {
+		_, err = f.Write([]byte{'\n'})
+	}
+	return err
+}
+
+func (l *seenContractsLog) append(contract regionContract) error {
+	if l == nil {
+		return nil
+	}
+	data, err := json.Marshal(contract)
+	if err != nil {
+		return err
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if _, err = l.file.Write(append(data, '\n')); err == nil {
+		l.records++
+	}
+	return err
+}
+
+// the log is outdated when most of its records refer to contracts that are no longer in the database
+func (l *seenContractsLog) isOutdated(actual int) bool {
+	if l == nil {
+		return false
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.records-actual > compactThreshold && l.records > actual*2
+}
+
+// replaces the log with the actual contracts, the new file is prepared aside so a crash does not lose the log
+func (l *seenContractsLog) compact(contracts []regionContract) error {
+	if l == nil {
+		return nil
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	var tmpPath = l.path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	var (
+		w   = bufio.NewWriter(f)
+		enc = json.NewEncoder(w)
+	)
+	for _, c := range contracts {
+		if err = enc.Encode(c); err != nil {
+			deferWithPrintError(f.Close)
+			return err
+		}
+	}
+	if err = w.Flush(); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, l.path); err != nil {
+		return err
+	}
+	newFile, err := os.OpenFile(l.path, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	deferWithPrintError(l.file.Close)
+	l.file = newFile
+	l.records = len(contracts)
+	return nil
+}
+
+func (l *seenContractsLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
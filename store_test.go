@@ -0,0 +1,162 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_seenContractsLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jitaScan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var path = filepath.Join(dir, "contracts.log")
+
+	seen, err := openSeenContractsLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := int64(1); id <= 3; id++ {
+		if err = seen.append(regionContract{Region: regionIdJita, contract: contract{Id: id, Type: itemExchange, Title: "Test"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = seen.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// simulate the record truncated by the killed process
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(`{"region":"10000002","contract_id":4,"ty`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	db, err := connectToDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen, err = openSeenContractsLog(path); err != nil {
+		t.Fatal(err)
+	}
+	defer seen.Close()
+	count, err := seen.replay(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 restored contracts, got %d", count)
+	}
+	if !isRegionKnownDB(db, regionIdJita) || isRegionKnownDB(db, "10000043") {
+		t.Error("unexpected known regions")
+	}
+	if isNewlyCreatedContractCheckDB(db, regionIdJita, contract{Id: 2}) {
+		t.Error("restored contract is considered as new")
+	}
+	if err = seen.append(regionContract{Region: regionIdJita, contract: contract{Id: 5, Type: itemExchange}}); err != nil {
+		t.Fatal(err)
+	}
+	if count, err = seen.replay(db); err != nil || count != 4 {
+		t.Errorf("expected 4 restored contracts after append, got %d (%v)", count, err)
+	}
+}
+
+func Test_seenContractsLog_compact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jitaScan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	seen, err := openSeenContractsLog(filepath.Join(dir, "contracts.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer seen.Close()
+	for id := int64(1); id <= compactThreshold+10; id++ {
+		if err = seen.append(regionContract{Region: regionIdJita, contract: contract{Id: id}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if seen.isOutdated(compactThreshold) {
+		t.Error("the log should not be compacted yet")
+	}
+	if !seen.isOutdated(5) {
+		t.Fatal("the log should be compacted")
+	}
+	var actual []regionContract
+	for id := int64(1); id <= 5; id++ {
+		actual = append(actual, regionContract{Region: regionIdJita, contract: contract{Id: id}})
+	}
+	if err = seen.compact(actual); err != nil {
+		t.Fatal(err)
+	}
+	if err = seen.append(regionContract{Region: regionIdJita, contract: contract{Id: 6}}); err != nil {
+		t.Fatal(err)
+	}
+	db, err := connectToDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, err := seen.replay(db); err != nil || count != 6 {
+		t.Errorf("expected 6 contracts after compaction, got %d (%v)", count, err)
+	}
+}
+
+func Test_readSeenContractsLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jitaScan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		path = filepath.Join(dir, "contracts.log")
+		// the monitoring is in the middle of writing the second record
+		data = `{"region":"10000002","contract_id":1,"type":"item_exchange"}` + "\n" + `{"region":"10000002","contract_id":2,"ty`
+	)
+	if err = ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := connectToDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := readSeenContractsLog(path, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 contract, got %d", count)
+	}
+	if got, err := ioutil.ReadFile(path); err != nil || string(got) != data {
+		t.Errorf("the log has been changed: %q, %v", got, err)
+	}
+	if count, err = readSeenContractsLog(filepath.Join(dir, "missing.log"), db); err != nil || count != 0 {
+		t.Errorf("expected no contracts from a missing log, got %d, %v", count, err)
+	}
+}
+
+func Test_loadSeenContracts_longRecord(t *testing.T) {
+	var (
+		// a contract with plenty of watched items does not fit the default buffer of bufio.Scanner
+		long = `{"region":"10000002","contract_id":1,"type":"item_exchange","title":"` + strings.Repeat("x", 100*1024) + `"}`
+		data = long + "\n" + `{"region":"10000002","contract_id":2,"type":"item_exchange"}` + "\n" + `{"region":"10000002","contract_id":3,"ty`
+	)
+	db, err := connectToDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := loadSeenContracts(strings.NewReader(data), "contracts.log", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 contracts, got %d", count)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -12,17 +13,24 @@ type (
 		Buyout              float64   `json:"buyout"`     // Auction
 		Collateral          float64   `json:"collateral"` // Couriers
 		Price               float64   `json:"price"`      // ItemExchange
+		Reward              float64   `json:"reward"`     // ItemExchange (want to buy) and Couriers
 		DateExpired         time.Time `json:"date_expired"`
 		DateIssued          time.Time `json:"date_issued"`
+		DaysToComplete      int32     `json:"days_to_complete"` // Couriers
 		ForCorporation      bool      `json:"for_corporation"`
+		IssuerId            int64     `json:"issuer_id"`
 		IssuerCorporationId int64     `json:"issuer_corporation_id"`
 		Title               string    `json:"title"`
 		Type                string    `json:"type"` // [ unknown, item_exchange, auction, courier, loan ]
 		Volume              float64   `json:"volume"`
+		StartLocationId     int64     `json:"start_location_id"`
+		EndLocationId       int64     `json:"end_location_id"`
+		// the contract as it was received from ESI, it is not stored
+		Raw json.RawMessage `json:"-"`
 	}
 	contractItem struct {
-		RecordId int64 `json:"record_id"`
-		// ItemId             *int64 `json:"item_id"`
+		RecordId           int64 `json:"record_id"`
+		ItemId             int64 `json:"item_id,omitempty"` // not present if the item is requested
 		IsBlueprintCopy    bool  `json:"is_blueprint_copy"`
 		IsIncluded         bool  `json:"is_included"`
 		MaterialEfficiency int32 `json:"material_efficiency"`
@@ -31,14 +39,49 @@ type (
 		Quantity           int32 `json:"quantity"`
 		TypeId             int64 `json:"type_id"`
 	}
+	// regionContract is a contract bound to the region where it was found, with the history of its observation
+	regionContract struct {
+		Region      string         `json:"region"`
+		FirstSeen   time.Time      `json:"first_seen"`
+		LastSeen    time.Time      `json:"last_seen"`
+		Disappeared time.Time      `json:"disappeared"`
+		Watched     []contractItem `json:"watched,omitempty"`
+		contract
+	}
 	registrySignal struct {
+		region   string
 		contract contract
 		items    []contractItem
+		wanted   bool    // the contract asks for our items
+		bid      float64 // the current bid of the ending auction
+		jumps    int     // the route length of the courier contract
+		warnings []string
+		// the contract is not suitable, it is reported only because of the warnings
+		suspicious bool
 	}
 	registryItem struct {
-		TypeId   int64   `json:"type_id"`
-		Price    float64 `json:"price"`
-		TypeName string  `json:"type_name"`
+		TypeId          int64   `json:"type_id"`
+		Price           float64 `json:"price"`          // a single run of a copy
+		OriginalPrice   float64 `json:"original_price"` // a single original
+		IgnoreCopies    bool    `json:"ignore_copies"`
+		IgnoreOriginals bool    `json:"ignore_originals"`
+		MinME           int32   `json:"min_me"`
+		MinTE           int32   `json:"min_te"`
+		MEBonus         float64 `json:"me_bonus"` // percent of the price per ME level above the minimum
+		TEBonus         float64 `json:"te_bonus"` // percent of the price per TE level above the minimum
+		MinRuns         int32   `json:"min_runs"`
+		MaxRuns         int32   `json:"max_runs"`
+		MinQuantity     int32   `json:"min_quantity"` // total quantity of the type in a contract
+		MaxQuantity     int32   `json:"max_quantity"`
+		// the prices we accept to sell our items to those who want to buy them
+		SellPrice         float64 `json:"sell_price"`
+		SellOriginalPrice float64 `json:"sell_original_price"`
+		TypeName          string  `json:"type_name"`
+	}
+	contractBid struct {
+		BidId   int64     `json:"bid_id"`
+		Amount  float64   `json:"amount"`
+		DateBid time.Time `json:"date_bid"`
 	}
 	itemType struct {
 		typeId   int64
@@ -52,10 +95,16 @@ type (
 
 const (
 	itemExchange = "item_exchange"
+	auction      = "auction"
+	courier      = "courier"
 )
 
-func isPublicItemExchangeContract(contract contract) bool {
-	return !contract.ForCorporation && contract.Type == itemExchange
+func isPublicTradeContract(contract contract) bool {
+	return !contract.ForCorporation && (contract.Type == itemExchange || contract.Type == auction)
+}
+
+func isPublicCourierContract(contract contract) bool {
+	return !contract.ForCorporation && contract.Type == courier
 }
 
 func getItemName(items []itemType, id int64) string {
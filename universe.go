@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+const (
+	apiStations = "/latest/universe/stations/"
+	apiRoute    = "/latest/route/"
+	apiSystems  = "/latest/universe/systems/"
+
+	// the IDs of player structures start here, they cannot be resolved without authorization
+	minStructureId = 1000000000000
+)
+
+var (
+	errUnknownLocation = errors.New("location cannot be resolved")
+	errNoRoute         = errors.New("no route between the systems")
+)
+
+type (
+	station struct {
+		StationId int64  `json:"station_id"`
+		SystemId  int64  `json:"system_id"`
+		Name      string `json:"name"`
+	}
+	solarSystem struct {
+		SystemId       int64   `json:"system_id"`
+		Name           string  `json:"name"`
+		SecurityStatus float64 `json:"security_status"`
+	}
+	// universeCache keeps the resolved locations and routes, they do not change while the utility is running
+	universeCache struct {
+		mux     sync.Mutex
+		systems map[int64]int64
+		routes  map[string]int
+		secure  map[int64]float64
+	}
+)
+
+func newUniverseCache() *universeCache {
+	return &universeCache{
+		systems: make(map[int64]int64),
+		routes:  make(map[string]int),
+		secure:  make(map[int64]float64),
+	}
+}
+
+func (u *universeCache) getSystem(locationId int64) (int64, bool) {
+	if u == nil {
+		return 0, false
+	}
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	systemId, ok := u.systems[locationId]
+	return systemId, ok
+}
+
+func (u *universeCache) setSystem(locationId, systemId int64) {
+	if u == nil {
+		return
+	}
+	u.mux.Lock()
+	u.systems[locationId] = systemId
+	u.mux.Unlock()
+}
+
+func (u *universeCache) getJumps(route string) (int, bool) {
+	if u == nil {
+		return 0, false
+	}
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	jumps, ok := u.routes[route]
+	return jumps, ok
+}
+
+func (u *universeCache) setJumps(route string, jumps int) {
+	if u == nil {
+		return
+	}
+	u.mux.Lock()
+	u.routes[route] = jumps
+	u.mux.Unlock()
+}
+
+func (u *universeCache) getSecurity(systemId int64) (float64, bool) {
+	if u == nil {
+		return 0, false
+	}
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	security, ok := u.secure[systemId]
+	return security, ok
+}
+
+func (u *universeCache) setSecurity(systemId int64, security float64) {
+	if u == nil {
+		return
+	}
+	u.mux.Lock()
+	u.secure[systemId] = security
+	u.mux.Unlock()
+}
+
+// returns the solar system of the NPC station
+// the unknown locations are remembered as well, ESI counts every 404 as an error
+func (c *eveConnector) getLocationSystem(ctx context.Context, locationId int64) (int64, error) {
+	if systemId, ok := c.universe.getSystem(locationId); ok {
+		if systemId == 0 {
+			return 0, errUnknownLocation
+		}
+		return systemId, nil
+	}
+	if locationId >= minStructureId {
+		return 0, errUnknownLocation
+	}
+	var data station
+	_, err := c.executeQuery(ctx, apiStations, strconv.FormatInt(locationId, 10), 1, &data)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if err == io.EOF || data.SystemId == 0 {
+		c.universe.setSystem(locationId, 0)
+		return 0, errUnknownLocation
+	}
+	c.universe.setSystem(locationId, data.SystemId)
+	return data.SystemId, nil
+}
+
+// returns the security status of the solar system
+func (c *eveConnector) getSystemSecurity(ctx context.Context, systemId int64) (float64, error) {
+	if security, ok := c.universe.getSecurity(systemId); ok {
+		return security, nil
+	}
+	var data solarSystem
+	_, err := c.executeQuery(ctx, apiSystems, strconv.FormatInt(systemId, 10), 1, &data)
+	if err == io.EOF {
+		return 0, errUnknownLocation
+	}
+	if err != nil {
+		return 0, err
+	}
+	c.universe.setSecurity(systemId, data.SecurityStatus)
+	return data.SecurityStatus, nil
+}
+
+// returns the number of jumps of the shortest route between two solar systems
+func (c *eveConnector) getJumps(ctx context.Context, from, to int64) (int, error) {
+	if from == to {
+		return 0, nil
+	}
+	route := fmt.Sprintf("%d/%d", from, to)
+	if jumps, ok := c.universe.getJumps(route); ok {
+		if jumps < 0 {
+			return 0, errNoRoute
+		}
+		return jumps, nil
+	}
+	var data []int64
+	_, err := c.executeQuery(ctx, apiRoute, route, 1, &data)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if err == io.EOF || len(data) == 0 {
+		// unreachable systems are remembered too, ESI counts every 404 as an error
+		c.universe.setJumps(route, -1)
+		return 0, errNoRoute
+	}
+	c.universe.setJumps(route, len(data)-1)
+	return len(data) - 1, nil
+}
+
+// returns the number of jumps between two stations
+func (c *eveConnector) getLocationJumps(ctx context.Context, from, to int64) (int, error) {
+	fromSystem, err := c.getLocationSystem(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+	toSystem, err := c.getLocationSystem(ctx, to)
+	if err != nil {
+		return 0, err
+	}
+	return c.getJumps(ctx, fromSystem, toSystem)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// pathClientTest responds with the data registered for the requested path
+type pathClientTest struct {
+	mux      sync.Mutex
+	data     map[string]string
+	requests int
+}
+
+func (p *pathClientTest) Do(r *http.Request) (*http.Response, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.requests++
+	// every path has a single page
+	data, ok := p.data[r.URL.Path]
+	if !ok || r.URL.Query().Get("page") != "1" {
+		return &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: ioutil.NopCloser(strings.NewReader(data))}, nil
+}
+
+func Test_getLocationJumps(t *testing.T) {
+	client := &pathClientTest{data: map[string]string{
+		"/latest/universe/stations/60003760": `{"station_id":60003760,"system_id":30000142,"name":"Jita IV - Moon 4"}`,
+		"/latest/universe/stations/60008494": `{"station_id":60008494,"system_id":30002187,"name":"Amarr VIII"}`,
+		"/latest/route/30000142/30002187":    `[30000142,30000144,30002187]`,
+	}}
+	eve := eveConnector{client: client, universe: newUniverseCache()}
+	for i := 0; i < 2; i++ {
+		jumps, err := eve.getLocationJumps(context.Background(), 60003760, 60008494)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if jumps != 2 {
+			t.Errorf("expected 2 jumps, got %d", jumps)
+		}
+	}
+	if client.requests != 3 {
+		t.Errorf("expected the route to be cached after 3 requests, got %d", client.requests)
+	}
+	if jumps, err := eve.getLocationJumps(context.Background(), 60003760, 60003760); err != nil || jumps != 0 {
+		t.Errorf("expected no jumps inside the system, got %d, %v", jumps, err)
+	}
+	if _, err := eve.getLocationJumps(context.Background(), 60003760, 1022734985679); err != errUnknownLocation {
+		t.Errorf("expected unknown location error, got %v", err)
+	}
+}
+
+func Test_getLocationSystem_unknown(t *testing.T) {
+	client := &pathClientTest{data: map[string]string{}}
+	eve := eveConnector{client: client, universe: newUniverseCache()}
+	for i := 0; i < 2; i++ {
+		if _, err := eve.getLocationSystem(context.Background(), 60000001); err != errUnknownLocation {
+			t.Errorf("expected unknown location error, got %v", err)
+		}
+	}
+	if _, err := eve.getSystemSecurity(context.Background(), 30000001); err != errUnknownLocation {
+		t.Errorf("expected unknown location error, got %v", err)
+	}
+	if client.requests != 2 {
+		t.Errorf("expected the unknown station to be requested once, got %d requests", client.requests)
+	}
+}
+
+func Test_getJumps_noRoute(t *testing.T) {
+	client := &pathClientTest{data: map[string]string{
+		"/latest/route/30000142/30000157": `[]`,
+	}}
+	eve := eveConnector{client: client, universe: newUniverseCache()}
+	for _, to := range []int64{30000157, 30000157, 30045328, 30045328} {
+		if _, err := eve.getJumps(context.Background(), 30000142, to); err != errNoRoute {
+			t.Errorf("expected no route error, got %v", err)
+		}
+	}
+	if client.requests != 2 {
+		t.Errorf("expected every route to be requested once, got %d requests", client.requests)
+	}
+}
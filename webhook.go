@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	notifyWebhook = "webhook"
+
+	webhookDiscord = "discord"
+	webhookSlack   = "slack"
+	webhookGeneric = "generic"
+
+	webhookTimeout   = time.Second * 10
+	webhookPerMinute = 30
+	// discord rejects longer messages
+	discordMaxContent = 2000
+	retryAfterHeader  = "Retry-After"
+)
+
+type (
+	// webhookConfig is a receiver of the alerts from the config file
+	webhookConfig struct {
+		Url       string `json:"url"`
+		Format    string `json:"format"`     // discord, slack or generic
+		PerMinute int    `json:"per_minute"` // the rate limit of the receiver
+	}
+	// webhookNotifier posts the alerts to the receiver, it is used by a single goroutine of the alerter
+	webhookNotifier struct {
+		client   httpClient
+		config   webhookConfig
+		registry map[int64]registryItem
+		retry    retryPolicy
+		interval time.Duration
+		next     time.Time
+	}
+	// webhookAlert is the payload of the generic webhook
+	webhookAlert struct {
+		Title      string          `json:"title"`
+		Text       string          `json:"text"`
+		Region     string          `json:"region"`
+		RegionName string          `json:"region_name"`
+		Wanted     bool            `json:"wanted,omitempty"`
+		Suspicious bool            `json:"suspicious,omitempty"`
+		Bid        float64         `json:"bid,omitempty"`
+		Jumps      int             `json:"jumps,omitempty"`
+		Warnings   []string        `json:"warnings,omitempty"`
+		Contract   contract        `json:"contract"`
+		Items      []contractItem  `json:"items,omitempty"`
+		Raw        json.RawMessage `json:"raw,omitempty"`
+	}
+	discordPayload struct {
+		Username        string                 `json:"username"`
+		Content         string                 `json:"content"`
+		AllowedMentions discordAllowedMentions `json:"allowed_mentions"`
+	}
+	// discordAllowedMentions with the empty list forbids any pings from the message
+	discordAllowedMentions struct {
+		Parse []string `json:"parse"`
+	}
+	slackPayload struct {
+		Text string `json:"text"`
+	}
+)
+
+func newWebhookNotifier(config webhookConfig, registry map[int64]registryItem, retry retryPolicy) (*webhookNotifier, error) {
+	// neither the URL nor the parser error is shown, the path contains a secret token of the receiver
+	if u, err := url.Parse(config.Url); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("wrong webhook URL")
+	}
+	switch config.Format {
+	case "":
+		config.Format = webhookGeneric
+	case webhookDiscord, webhookSlack, webhookGeneric:
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", config.Format)
+	}
+	if config.PerMinute <= 0 {
+		config.PerMinute = webhookPerMinute
+	}
+	if retry.attempts < 1 {
+		retry.attempts = 1
+	}
+	return &webhookNotifier{
+		client:   &http.Client{Timeout: webhookTimeout},
+		config:   config,
+		registry: registry,
+		retry:    retry,
+		interval: time.Minute / time.Duration(config.PerMinute),
+	}, nil
+}
+
+func (h *webhookNotifier) name() string {
+	// the rest of the URL is a secret token of the receiver
+	if u, err := url.Parse(h.config.Url); err == nil {
+		return fmt.Sprintf("%s %s", notifyWebhook, u.Host)
+	}
+	return notifyWebhook
+}
+
+var (
+	// the title of the contract is chosen by its issuer, backticks would break out of the code block
+	discordEscaper = strings.NewReplacer("`", "'")
+	// the angle brackets are the control sequences of slack like <!channel>
+	slackEscaper = strings.NewReplacer("`", "'", "&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+func (h *webhookNotifier) makePayload(sig registrySignal) ([]byte, error) {
+	title, summary := getAlertSummary(sig)
+	switch h.config.Format {
+	case webhookDiscord:
+		var text strings.Builder
+		formatAlert(&text, h.registry, sig)
+		title, summary = discordEscaper.Replace(title), discordEscaper.Replace(summary)
+		content := fmt.Sprintf("**%s**\n%s\n```\n%s```", title, summary, discordEscaper.Replace(text.String()))
+		if len(content) > discordMaxContent {
+			content = fmt.Sprintf("**%s**\n%s", title, summary)
+		}
+		return json.Marshal(discordPayload{
+			Username:        "jitaScan",
+			Content:         content,
+			AllowedMentions: discordAllowedMentions{Parse: []string{}},
+		})
+	case webhookSlack:
+		var text strings.Builder
+		formatAlert(&text, h.registry, sig)
+		return json.Marshal(slackPayload{Text: fmt.Sprintf("*%s*\n%s\n```%s```",
+			slackEscaper.Replace(title), slackEscaper.Replace(summary), slackEscaper.Replace(text.String()))})
+	}
+	return json.Marshal(webhookAlert{
+		Title:      title,
+		Text:       summary,
+		Region:     sig.region,
+		RegionName: getRegionName(sig.region),
+		Wanted:     sig.wanted,
+		Suspicious: sig.suspicious,
+		Bid:        sig.bid,
+		Jumps:      sig.jumps,
+		Warnings:   sig.warnings,
+		Contract:   sig.contract,
+		Items:      sig.items,
+		Raw:        sig.contract.Raw,
+	})
+}
+
+// returns the delay requested by the receiver, zero if it is unknown
+func getRetryAfter(header http.Header) time.Duration {
+	if seconds, err := strconv.ParseFloat(header.Get(retryAfterHeader), 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return 0
+}
+
+// sends the payload once, the delay requested by the receiver is returned along with the error
+func (h *webhookNotifier) post(payload []byte) (time.Duration, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.config.Url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		// the URL contains the secret token of the receiver, it must not get into the log
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return 0, true, fmt.Errorf("%s: %s", urlErr.Op, urlErr.Err)
+		}
+		return 0, true, err
+	}
+	defer deferWithPrintError(resp.Body.Close)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, false, nil
+	}
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return getRetryAfter(resp.Header), retryable, errors.New(resp.Status)
+}
+
+// waits for the given time, returns false if the context is cancelled earlier
+func waitContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// every post including retries respects the rate limit of the receiver,
+// the pending alerts are still sent on shutdown, but without waiting and retries
+func (h *webhookNotifier) notify(ctx context.Context, sig registrySignal) error {
+	payload, err := h.makePayload(sig)
+	if err != nil {
+		return err
+	}
+	var delay time.Duration
+	for attempt := 0; ; attempt++ {
+		if d := time.Until(h.next); d > delay {
+			delay = d
+		}
+		if !waitContext(ctx, delay) && attempt > 0 {
+			return err
+		}
+		var (
+			retryAfter time.Duration
+			retryable  bool
+		)
+		retryAfter, retryable, err = h.post(payload)
+		h.next = time.Now().Add(h.interval)
+		if err == nil || !retryable || attempt+1 >= h.retry.attempts {
+			return err
+		}
+		if delay = h.retry.delay(attempt); retryAfter > delay {
+			delay = retryAfter
+		}
+	}
+}
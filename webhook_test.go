@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiverTest fails the given number of requests and remembers the rest
+type webhookReceiverTest struct {
+	mux      sync.Mutex
+	failures int
+	bodies   [][]byte
+	times    []time.Time
+	requests []time.Time
+}
+
+func (r *webhookReceiverTest) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.requests = append(r.requests, time.Now())
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	r.bodies = append(r.bodies, body)
+	r.times = append(r.times, time.Now())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func makeWebhookSignal() registrySignal {
+	return registrySignal{
+		region: "10000002",
+		contract: contract{
+			Id:    152093844,
+			Title: "Raven BPC",
+			Type:  itemExchange,
+			Price: 45000000,
+			Raw:   json.RawMessage(`{"contract_id":152093844}`),
+		},
+		items: []contractItem{{TypeId: 688, IsIncluded: true, Runs: 1, Quantity: 1}},
+	}
+}
+
+func Test_webhookNotifier(t *testing.T) {
+	receiver := &webhookReceiverTest{failures: 2}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	webhook, err := newWebhookNotifier(webhookConfig{Url: server.URL, PerMinute: 600}, nil, retryPolicy{attempts: 3, maxDelay: time.Millisecond * 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = webhook.notify(context.Background(), makeWebhookSignal()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(receiver.bodies) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(receiver.bodies))
+	}
+	if d := receiver.times[1].Sub(receiver.times[0]); d < time.Millisecond*90 {
+		t.Errorf("the rate limit is not respected, the second alert came in %s", d)
+	}
+	var alert webhookAlert
+	if err = json.Unmarshal(receiver.bodies[0], &alert); err != nil {
+		t.Fatal(err)
+	}
+	if alert.Contract.Id != 152093844 || alert.RegionName != "The Forge" || len(alert.Items) != 1 || string(alert.Raw) != `{"contract_id":152093844}` {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+}
+
+func Test_webhookNotifier_giveUp(t *testing.T) {
+	receiver := &webhookReceiverTest{failures: 5}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	webhook, err := newWebhookNotifier(webhookConfig{Url: server.URL, PerMinute: 6000}, nil, retryPolicy{attempts: 2, maxDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = webhook.notify(context.Background(), makeWebhookSignal()); err == nil {
+		t.Error("expected an error")
+	}
+	if receiver.failures != 3 {
+		t.Errorf("expected 2 attempts, got %d", 5-receiver.failures)
+	}
+}
+
+func Test_webhookNotifier_formats(t *testing.T) {
+	tests := []struct {
+		format string
+		field  string
+	}{
+		{format: webhookDiscord, field: "content"},
+		{format: webhookSlack, field: "text"},
+		{format: webhookGeneric, field: "contract"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			receiver := &webhookReceiverTest{}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+			webhook, err := newWebhookNotifier(webhookConfig{Url: server.URL, Format: tt.format}, nil, retryPolicy{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = webhook.notify(context.Background(), makeWebhookSignal()); err != nil {
+				t.Fatal(err)
+			}
+			var payload map[string]json.RawMessage
+			if err = json.Unmarshal(receiver.bodies[0], &payload); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := payload[tt.field]; !ok {
+				t.Errorf("expected %q in the payload %s", tt.field, receiver.bodies[0])
+			}
+		})
+	}
+	if _, err := newWebhookNotifier(webhookConfig{Url: "http://localhost", Format: "teams"}, nil, retryPolicy{}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func Test_webhookNotifier_hostileTitle(t *testing.T) {
+	var sig = makeWebhookSignal()
+	sig.contract.Title = "@everyone <!channel> ```free & cheap```"
+	for _, format := range []string{webhookDiscord, webhookSlack} {
+		t.Run(format, func(t *testing.T) {
+			receiver := &webhookReceiverTest{}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+			webhook, err := newWebhookNotifier(webhookConfig{Url: server.URL, Format: format}, nil, retryPolicy{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = webhook.notify(context.Background(), sig); err != nil {
+				t.Fatal(err)
+			}
+			var payload struct {
+				Content         string `json:"content"`
+				Text            string `json:"text"`
+				AllowedMentions *struct {
+					Parse []string `json:"parse"`
+				} `json:"allowed_mentions"`
+			}
+			if err = json.Unmarshal(receiver.bodies[0], &payload); err != nil {
+				t.Fatal(err)
+			}
+			message := payload.Content + payload.Text
+			// the only code block is the one of the alert text
+			if n := strings.Count(message, "```"); n != 2 {
+				t.Errorf("the title breaks the code block: %s", message)
+			}
+			switch format {
+			case webhookDiscord:
+				if payload.AllowedMentions == nil || payload.AllowedMentions.Parse == nil || len(payload.AllowedMentions.Parse) != 0 {
+					t.Errorf("mentions are not forbidden: %s", receiver.bodies[0])
+				}
+			case webhookSlack:
+				if strings.Contains(message, "<!channel>") || strings.Contains(message, "& cheap") {
+					t.Errorf("the title is not escaped: %s", message)
+				}
+			}
+		})
+	}
+}
+
+func Test_webhookNotifier_hideToken(t *testing.T) {
+	server := httptest.NewServer(&webhookReceiverTest{})
+	// nobody listens to the address anymore
+	server.Close()
+	webhook, err := newWebhookNotifier(webhookConfig{Url: server.URL + "/api/webhooks/ID/TOKEN"}, nil, retryPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = webhook.notify(context.Background(), makeWebhookSignal())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if strings.Contains(err.Error(), "TOKEN") || strings.Contains(err.Error(), "/api/webhooks") {
+		t.Errorf("the error reveals the webhook URL: %s", err)
+	}
+	for _, wrongUrl := range []string{"/api/webhooks/ID/TOKEN", "http://localhost/api/webhooks/ID/TOKEN%zz"} {
+		_, err = newWebhookNotifier(webhookConfig{Url: wrongUrl}, nil, retryPolicy{})
+		if err == nil {
+			t.Fatalf("expected an error for %s", wrongUrl)
+		}
+		if strings.Contains(err.Error(), "TOKEN") || strings.Contains(err.Error(), "/api/webhooks") {
+			t.Errorf("the error reveals the webhook URL: %s", err)
+		}
+	}
+}
+
+func Test_webhookNotifier_retryRate(t *testing.T) {
+	receiver := &webhookReceiverTest{failures: 2}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	webhook, err := newWebhookNotifier(webhookConfig{Url: server.URL, PerMinute: 600}, nil, retryPolicy{attempts: 3, maxDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = webhook.notify(context.Background(), makeWebhookSignal()); err != nil {
+		t.Fatal(err)
+	}
+	if len(receiver.requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(receiver.requests))
+	}
+	for i := 1; i < len(receiver.requests); i++ {
+		if d := receiver.requests[i].Sub(receiver.requests[i-1]); d < time.Millisecond*90 {
+			t.Errorf("the retry %d came in %s, the rate limit is not respected", i, d)
+		}
+	}
+}